@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+
+	"github.com/flowable/flowable-external-client-golang/flowable"
+)
+
+// commands lists the subcommands in the order shown by the usage.
+var commands = []command{
+	{name: "list", summary: "list jobs", setup: setupList},
+	{name: "get", args: "<id>", summary: "show a job", setup: setupGet},
+	{name: "stacktrace", args: "<id>", summary: "print the exception stacktrace of a job", setup: setupStacktrace},
+	{name: "acquire", summary: "acquire and lock jobs of a topic", setup: setupAcquire},
+	{name: "complete", args: "<id>", summary: "complete an acquired job", setup: setupReport(flowable.HandlerSuccess)},
+	{name: "fail", args: "<id>", summary: "fail an acquired job", setup: setupReport(flowable.HandlerFail)},
+	{name: "bpmn-error", args: "<id>", summary: "throw a BPMN error for an acquired job", setup: setupReport(flowable.HandlerBPMNError)},
+	{name: "cmmn-terminate", args: "<id>", summary: "terminate the plan item of an acquired job", setup: setupReport(flowable.HandlerCMMNTerminate)},
+	{name: "doctor", summary: "check the connection, credentials and clock against Flowable", setup: setupDoctor},
+}
+
+func setupList(fs *flag.FlagSet) func(c *cli, args []string) error {
+	var f flowable.JobFilter
+	fs.StringVar(&f.Id, "id", "", "only the job with this id")
+	fs.StringVar(&f.ProcessInstanceId, "process-instance-id", "", "only jobs of this process instance")
+	fs.StringVar(&f.ExecutionId, "execution-id", "", "only jobs of this execution")
+	fs.StringVar(&f.ScopeId, "scope-id", "", "only jobs of this scope, e.g. a case instance")
+	fs.StringVar(&f.SubScopeId, "sub-scope-id", "", "only jobs of this sub scope, e.g. a plan item instance")
+	fs.StringVar(&f.ScopeType, "scope-type", "", "only jobs of this scope type, e.g. cmmn")
+	fs.StringVar(&f.ElementId, "element-id", "", "only jobs of this activity or plan item id")
+	fs.StringVar(&f.ElementName, "element-name", "", "only jobs of this activity or plan item name")
+	fs.StringVar(&f.TenantId, "tenant-id", "", "only jobs of this tenant")
+	fs.BoolVar(&f.WithException, "with-exception", false, "only jobs that failed with an exception")
+	fs.StringVar(&f.ExceptionMessage, "exception-message", "", "only jobs with this exception message")
+	fs.BoolVar(&f.Locked, "locked", false, "only locked jobs")
+	fs.BoolVar(&f.Unlocked, "unlocked", false, "only unlocked jobs")
+	fs.StringVar(&f.Sort, "sort", "", "sort field, e.g. id or dueDate")
+	fs.StringVar(&f.Order, "order", "", "sort order: asc or desc")
+	fs.IntVar(&f.Start, "start", 0, "index of the first job")
+	fs.IntVar(&f.Size, "size", 0, "maximum number of jobs")
+	return func(c *cli, args []string) error {
+		if len(args) != 0 {
+			return fmt.Errorf("%w: unexpected arguments %q", errUsage, args)
+		}
+		status, body, err := flowable.List_jobs_filtered(c.url, f)
+		if err := checkResponse(status, body, err); err != nil {
+			return err
+		}
+		return c.printJobList(body)
+	}
+}
+
+func setupGet(fs *flag.FlagSet) func(c *cli, args []string) error {
+	return func(c *cli, args []string) error {
+		id, err := jobIdArg(args)
+		if err != nil {
+			return err
+		}
+		status, body, err := flowable.Get_job(c.url, id)
+		if err := checkResponse(status, body, err); err != nil {
+			return err
+		}
+		return c.printJob(body)
+	}
+}
+
+func setupStacktrace(fs *flag.FlagSet) func(c *cli, args []string) error {
+	return func(c *cli, args []string) error {
+		id, err := jobIdArg(args)
+		if err != nil {
+			return err
+		}
+		status, body, err := flowable.Get_job_stacktrace(c.url, id)
+		if err := checkResponse(status, body, err); err != nil {
+			return err
+		}
+		if c.output == "json" {
+			return c.printJSON(map[string]string{"jobId": id, "stacktrace": body})
+		}
+		fmt.Fprintln(c.stdout, body)
+		return nil
+	}
+}
+
+func setupAcquire(fs *flag.FlagSet) func(c *cli, args []string) error {
+	req := flowable.AcquireRequest{}
+	fs.StringVar(&req.Topic, "topic", "", "topic to acquire jobs of (required)")
+	fs.IntVar(&req.NumberOfTasks, "n", 1, "maximum number of jobs")
+	fs.StringVar(&req.LockDuration, "lock-duration", "PT5M", "ISO-8601 lock duration")
+	fs.IntVar(&req.NumberOfRetries, "retries", 5, "number of retries when the acquire conflicts")
+	fs.StringVar(&req.ScopeType, "scope-type", "", "only jobs of this scope type: bpmn or cmmn")
+	return func(c *cli, args []string) error {
+		if len(args) != 0 {
+			return fmt.Errorf("%w: unexpected arguments %q", errUsage, args)
+		}
+		if req.Topic == "" {
+			return fmt.Errorf("%w: -topic is required", errUsage)
+		}
+		req.URL = c.url
+		req.WorkerId = c.workerId
+		_, body, status, err := flowable.Acquire_jobs(req)
+		if err := checkResponse(status, body, err); err != nil {
+			return err
+		}
+		return c.printAcquired(body)
+	}
+}
+
+// setupReport returns the setup of the command reporting resStatus.
+func setupReport(resStatus flowable.HandlerStatus) func(fs *flag.FlagSet) func(c *cli, args []string) error {
+	return func(fs *flag.FlagSet) func(c *cli, args []string) error {
+		var vars variableFlags
+		res := &flowable.HandlerResult{}
+		retries := -1
+		fs.Var(&vars, "var", "variable as name=value or name:type=value; repeatable")
+		fs.Func("vars", "JSON file with variables, as an array of {name, type, value} or an object", vars.addFile)
+		switch resStatus {
+		case flowable.HandlerFail:
+			fs.StringVar(&res.ErrorMessage, "message", "", "error message")
+			fs.StringVar(&res.ErrorDetails, "details", "", "error details")
+			fs.IntVar(&retries, "retries", -1, "remaining retries (default: decrement)")
+			fs.StringVar(&res.RetryTimeout, "retry-timeout", "", "ISO-8601 duration before the job is retried")
+		case flowable.HandlerBPMNError:
+			fs.StringVar(&res.ErrorCode, "code", "", "BPMN error code (default: bpmnError)")
+		}
+		return func(c *cli, args []string) error {
+			id, err := jobIdArg(args)
+			if err != nil {
+				return err
+			}
+			res.WorkerId = c.workerId
+			res.Variables = vars.list
+			if retries >= 0 {
+				res.Retries = &retries
+			}
+			status, body, err := flowable.Report_job(c.url, id, resStatus, res)
+			if err := checkResponse(status, body, err); err != nil {
+				return err
+			}
+			if c.output == "json" {
+				return c.printJSON(map[string]interface{}{"jobId": id, "outcome": resStatus, "status": status})
+			}
+			fmt.Fprintf(c.stdout, "job %s: %s reported (HTTP %d)\n", id, resStatus, status)
+			return nil
+		}
+	}
+}
+
+func setupDoctor(fs *flag.FlagSet) func(c *cli, args []string) error {
+	return func(c *cli, args []string) error {
+		if len(args) != 0 {
+			return fmt.Errorf("%w: unexpected arguments %q", errUsage, args)
+		}
+		d := flowable.Diagnose(context.Background(), c.url)
+		if c.output == "json" {
+			if err := c.printJSON(d); err != nil {
+				return err
+			}
+		} else {
+			fmt.Fprint(c.stdout, d)
+		}
+		if !d.OK {
+			return errors.New("diagnosis failed")
+		}
+		return nil
+	}
+}
+
+// jobIdArg returns the single job id of args.
+func jobIdArg(args []string) (string, error) {
+	if len(args) != 1 || args[0] == "" {
+		return "", fmt.Errorf("%w: expected exactly one job id", errUsage)
+	}
+	return args[0], nil
+}
+
+// checkResponse turns a failed call or a non-2xx status into an error.
+func checkResponse(status int, body string, err error) error {
+	if err != nil {
+		return err
+	}
+	if status < 200 || status >= 300 {
+		msg := body
+		var e struct {
+			Message   string `json:"message"`
+			Exception string `json:"exception"`
+		}
+		if json.Unmarshal([]byte(body), &e) == nil && (e.Message != "" || e.Exception != "") {
+			msg = e.Message
+			if e.Exception != "" {
+				msg += ": " + e.Exception
+			}
+		}
+		return fmt.Errorf("HTTP %d %s: %s", status, http.StatusText(status), msg)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+// Command flowable-jobs inspects and operates on Flowable external worker jobs
+// through the external job REST API.
+//
+// Usage:
+//
+//	flowable-jobs <command> [flags] [arguments]
+//
+// Commands:
+//
+//	list                   list jobs, filtered with flags such as -process-instance-id
+//	get <id>               show a job
+//	stacktrace <id>        print the exception stacktrace of a job
+//	acquire -topic <t>     acquire and lock jobs of a topic
+//	complete <id>          complete an acquired job
+//	fail <id>              fail an acquired job
+//	bpmn-error <id>        throw a BPMN error for an acquired job
+//	cmmn-terminate <id>    terminate the plan item of an acquired job
+//	doctor                 check DNS, TCP, TLS, the API path, credentials and clock
+//
+// Every command accepts -url, -user, -password, -token, -ca-file and -o
+// (table or json). The connection flags default to FLOWABLE_URLS (the first
+// URL), FLOWABLE_USERNAME, FLOWABLE_PASSWORD, FLOWABLE_TOKEN and
+// FLOWABLE_TLS_CA_FILE. Variables are given with -var name=value (repeatable)
+// or -vars file.json; run a command with -h for its flags.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/flowable/flowable-external-client-golang/flowable"
+)
+
+// Exit codes.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// command is one subcommand of the tool.
+type command struct {
+	name    string
+	args    string
+	summary string
+	// setup registers the flags of the command and returns the function
+	// running it with the positional arguments.
+	setup func(fs *flag.FlagSet) func(c *cli, args []string) error
+}
+
+// cli holds the settings shared by all commands.
+type cli struct {
+	stdout, stderr io.Writer
+	url            string
+	user           string
+	password       string
+	token          string
+	caFile         string
+	output         string
+	workerId       string
+}
+
+// errUsage marks errors caused by wrong arguments.
+var errUsage = errors.New("usage")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command line args and returns the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	c := &cli{stdout: stdout, stderr: stderr}
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		c.usage()
+		return exitUsage
+	}
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		fmt.Fprintf(stderr, "flowable-jobs: unknown command %q\n\n", args[0])
+		c.usage()
+		return exitUsage
+	}
+	fs := flag.NewFlagSet("flowable-jobs "+cmd.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: flowable-jobs %s [flags] %s\n\n%s\n\nFlags:\n", cmd.name, cmd.args, cmd.summary)
+		fs.PrintDefaults()
+	}
+	c.connectionFlags(fs)
+	action := cmd.setup(fs)
+	positional, err := parseArgs(fs, args[1:])
+	if err != nil {
+		return exitUsage
+	}
+	if err := c.connect(); err != nil {
+		fmt.Fprintf(stderr, "flowable-jobs: %v\n", err)
+		return exitUsage
+	}
+	if err := action(c, positional); err != nil {
+		fmt.Fprintf(stderr, "flowable-jobs %s: %v\n", cmd.name, err)
+		if errors.Is(err, errUsage) {
+			fs.Usage()
+			return exitUsage
+		}
+		return exitError
+	}
+	return exitOK
+}
+
+// parseArgs parses fs from args and returns the positional arguments. Unlike
+// fs.Parse it also accepts flags after the positional arguments, as in
+// "get <id> -o json".
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// connectionFlags registers the flags shared by all commands.
+func (c *cli) connectionFlags(fs *flag.FlagSet) {
+	url := ""
+	if urls := strings.Split(os.Getenv("FLOWABLE_URLS"), ","); len(urls) > 0 {
+		url = strings.TrimSpace(urls[0])
+	}
+	if url == "" {
+		url = "http://localhost:8090"
+	}
+	hostname, _ := os.Hostname()
+	fs.StringVar(&c.url, "url", url, "base URL of Flowable (env FLOWABLE_URLS)")
+	fs.StringVar(&c.user, "user", os.Getenv("FLOWABLE_USERNAME"), "basic auth user (env FLOWABLE_USERNAME)")
+	fs.StringVar(&c.password, "password", os.Getenv("FLOWABLE_PASSWORD"), "basic auth password (env FLOWABLE_PASSWORD)")
+	fs.StringVar(&c.token, "token", os.Getenv("FLOWABLE_TOKEN"), "bearer token (env FLOWABLE_TOKEN)")
+	fs.StringVar(&c.caFile, "ca-file", os.Getenv("FLOWABLE_TLS_CA_FILE"), "PEM bundle of additional trusted CAs (env FLOWABLE_TLS_CA_FILE)")
+	fs.StringVar(&c.output, "o", "table", "output format: table or json")
+	fs.StringVar(&c.workerId, "worker-id", "flowable-jobs@"+hostname, "worker id used to acquire and report jobs")
+}
+
+// connect validates the shared flags and configures the library with them.
+func (c *cli) connect() error {
+	if c.output != "table" && c.output != "json" {
+		return fmt.Errorf("-o must be table or json, got %q", c.output)
+	}
+	if err := flowable.ValidateBaseURL(c.url); err != nil {
+		return err
+	}
+	flowable.SetEnableLogging(false)
+	if c.user != "" || c.password != "" {
+		flowable.SetAuth(c.user, c.password)
+	}
+	if c.token != "" {
+		flowable.SetBearerToken(c.token)
+	}
+	if c.caFile != "" {
+		return flowable.ConfigureTLS(flowable.TLSOptions{CAFile: c.caFile})
+	}
+	return nil
+}
+
+func (c *cli) usage() {
+	fmt.Fprintln(c.stderr, "Usage: flowable-jobs <command> [flags] [arguments]\n\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(c.stderr, "  %-16s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(c.stderr, "\nRun flowable-jobs <command> -h for the flags of a command.")
+}
+
+// findCommand returns the command called name, or nil.
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
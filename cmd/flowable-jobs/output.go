@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// maxCellWidth truncates long table cells such as exception messages.
+const maxCellWidth = 60
+
+// printJSON writes v as indented JSON.
+func (c *cli) printJSON(v interface{}) error {
+	enc := json.NewEncoder(c.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// printRawJSON writes a JSON response body indented, keeping numbers as sent.
+func (c *cli) printRawJSON(body string) error {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(body), "", "  "); err != nil {
+		return fmt.Errorf("invalid JSON response: %w", err)
+	}
+	buf.WriteByte('\n')
+	_, err := buf.WriteTo(c.stdout)
+	return err
+}
+
+// printJobList prints the body of a jobs list response.
+func (c *cli) printJobList(body string) error {
+	if c.output == "json" {
+		return c.printRawJSON(body)
+	}
+	var page struct {
+		Data  []map[string]interface{} `json:"data"`
+		Total int                      `json:"total"`
+	}
+	if err := decodeJSON(body, &page); err != nil {
+		return err
+	}
+	c.printJobTable(page.Data, []string{"ID", "SCOPE", "INSTANCE", "ELEMENT", "RETRIES", "LOCK OWNER", "LOCK EXPIRES", "EXCEPTION"},
+		func(job map[string]interface{}) []string {
+			return []string{field(job, "id"), scopeType(job), instanceId(job), field(job, "elementId"), field(job, "retries"),
+				field(job, "lockOwner"), field(job, "lockExpirationTime"), field(job, "exceptionMessage")}
+		})
+	fmt.Fprintf(c.stdout, "%d of %d jobs\n", len(page.Data), page.Total)
+	return nil
+}
+
+// printAcquired prints the body of an acquire response.
+func (c *cli) printAcquired(body string) error {
+	if c.output == "json" {
+		return c.printRawJSON(body)
+	}
+	var jobs []map[string]interface{}
+	if err := decodeJSON(body, &jobs); err != nil {
+		return err
+	}
+	c.printJobTable(jobs, []string{"ID", "SCOPE", "INSTANCE", "ELEMENT", "LOCK EXPIRES", "VARIABLES"},
+		func(job map[string]interface{}) []string {
+			vars, _ := job["variables"].([]interface{})
+			return []string{field(job, "id"), scopeType(job), instanceId(job), field(job, "elementId"),
+				field(job, "lockExpirationTime"), fmt.Sprint(len(vars))}
+		})
+	fmt.Fprintf(c.stdout, "%d jobs acquired\n", len(jobs))
+	return nil
+}
+
+// printJob prints the body of a get job response as one field per line.
+func (c *cli) printJob(body string) error {
+	if c.output == "json" {
+		return c.printRawJSON(body)
+	}
+	var job map[string]interface{}
+	if err := decodeJSON(body, &job); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(job))
+	for k := range job {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	tw := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	for _, k := range keys {
+		fmt.Fprintf(tw, "%s\t%s\n", k, field(job, k))
+	}
+	return tw.Flush()
+}
+
+// printJobTable prints one row per job, made by row.
+func (c *cli) printJobTable(jobs []map[string]interface{}, header []string, row func(map[string]interface{}) []string) {
+	tw := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, job := range jobs {
+		cells := row(job)
+		for i, cell := range cells {
+			cells[i] = truncate(cell)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	tw.Flush()
+}
+
+// decodeJSON unmarshals a response body, keeping numbers as sent.
+func decodeJSON(body string, v interface{}) error {
+	dec := json.NewDecoder(strings.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid JSON response: %w", err)
+	}
+	return nil
+}
+
+// field formats the value of key in job for a table cell.
+func field(job map[string]interface{}, key string) string {
+	switch v := job[key].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number, bool:
+		return fmt.Sprint(v)
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
+
+// scopeType returns "bpmn" for process jobs and the scope type otherwise.
+func scopeType(job map[string]interface{}) string {
+	if t := field(job, "scopeType"); t != "" {
+		return t
+	}
+	if field(job, "processInstanceId") != "" {
+		return "bpmn"
+	}
+	return ""
+}
+
+// instanceId returns the process instance id, or the scope id for case jobs.
+func instanceId(job map[string]interface{}) string {
+	if id := field(job, "processInstanceId"); id != "" {
+		return id
+	}
+	return field(job, "scopeId")
+}
+
+// truncate shortens s to maxCellWidth runes and keeps it on one line.
+func truncate(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > maxCellWidth {
+		return string(r[:maxCellWidth-3]) + "..."
+	}
+	return s
+}
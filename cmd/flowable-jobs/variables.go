@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/flowable/flowable-external-client-golang/flowable"
+)
+
+// variableFlags collects the variables of the -var and -vars flags, in order.
+type variableFlags struct {
+	list []flowable.HandlerVariable
+}
+
+func (v *variableFlags) String() string { return "" }
+
+// Set parses name=value or name:type=value. Without a type, a value that is
+// valid JSON is sent as long, double, boolean or json, and anything else as a
+// string; quote a value to force a string, as in -var 'id="0042"'.
+func (v *variableFlags) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("expected name=value or name:type=value, got %q", s)
+	}
+	name, varType, typed := strings.Cut(name, ":")
+	if typed {
+		val, err := typedValue(varType, value)
+		if err != nil {
+			return fmt.Errorf("variable %s: %w", name, err)
+		}
+		v.list = append(v.list, flowable.HandlerVariable{Name: name, Type: varType, Value: val})
+		return nil
+	}
+	val, varType := inferValue(value)
+	v.list = append(v.list, flowable.HandlerVariable{Name: name, Type: varType, Value: val})
+	return nil
+}
+
+// addFile adds the variables of a JSON file, given as an array of
+// {"name", "type", "value"} objects or as an object of {"type", "value"} objects.
+func (v *variableFlags) addFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	vars, err := flowable.ExtractVariablesFromBody(`{"variables":` + string(b) + `}`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	v.list = append(v.list, vars...)
+	return nil
+}
+
+// inferValue decodes a value given without a type.
+func inferValue(s string) (interface{}, string) {
+	dec := json.NewDecoder(bytes.NewReader([]byte(s)))
+	dec.UseNumber()
+	var val interface{}
+	if err := dec.Decode(&val); err != nil || dec.More() {
+		return s, "string"
+	}
+	switch val := val.(type) {
+	case json.Number:
+		if _, err := val.Int64(); err == nil {
+			return val, "long"
+		}
+		return val, "double"
+	case bool:
+		return val, "boolean"
+	case string:
+		return val, "string"
+	case nil:
+		return s, "string"
+	default:
+		return val, "json"
+	}
+}
+
+// typedValue decodes the value of a variable of an explicit type.
+func typedValue(varType, s string) (interface{}, error) {
+	switch varType {
+	case "string", "date", "localdate", "instant", "uuid":
+		return s, nil
+	case "integer", "short", "long", "double", "bigdecimal", "biginteger":
+		n := json.Number(s)
+		if _, err := n.Float64(); err != nil {
+			return nil, fmt.Errorf("%q is not a number", s)
+		}
+		return n, nil
+	case "boolean":
+		switch s {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%q is not a boolean", s)
+	case "json":
+		var val interface{}
+		dec := json.NewDecoder(strings.NewReader(s))
+		dec.UseNumber()
+		if err := dec.Decode(&val); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		return val, nil
+	}
+	return nil, fmt.Errorf("unknown type %q", varType)
+}
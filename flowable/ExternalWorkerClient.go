@@ -1,14 +1,19 @@
 package flowable
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
-const job_api = "/external-job-api"
-
 // HandlerStatus indicates how the handler processed a job.
 type HandlerStatus string
 
@@ -28,15 +33,22 @@ type HandlerVariable struct {
 
 // HandlerResult is the structured response returned by the handler.
 type HandlerResult struct {
-	Status    HandlerStatus     `json:"status"`
-	WorkerId  string            `json:"workerId,omitempty"`
-	Variables []HandlerVariable `json:"variables"`
-	ErrorCode string            `json:"errorCode,omitempty"`
+	Status       HandlerStatus     `json:"status"`
+	WorkerId     string            `json:"workerId,omitempty"`
+	Variables    []HandlerVariable `json:"variables"`
+	ErrorCode    string            `json:"errorCode,omitempty"`
+	ErrorMessage string            `json:"errorMessage,omitempty"`
+	ErrorDetails string            `json:"errorDetails,omitempty"`
+	Retries      *int              `json:"retries,omitempty"`
+	RetryTimeout string            `json:"retryTimeout,omitempty"`
 }
 
 // Callback function type. The handler returns a HandlerStatus and an optional structured result.
 type ResponseHandler func(status int, body string) (HandlerStatus, *HandlerResult)
 
+// ContextHandler is a ResponseHandler that also receives a context, used by SubscribeContext.
+type ContextHandler func(ctx context.Context, status int, body string) (HandlerStatus, *HandlerResult)
+
 // AcquireRequest represents the body sent to the acquire endpoint.
 type AcquireRequest struct {
 	Topic           string `json:"topic"`
@@ -46,290 +58,502 @@ type AcquireRequest struct {
 	WorkerId        string `json:"workerId"`
 	ScopeType       string `json:"scopeType"`
 	// Connection and runtime settings (not sent in JSON body)
-	URL      string        `json:"-"`
+	URL string `json:"-"`
+	// URLs are the base URLs of further Flowable nodes. Acquires rotate over
+	// the healthy nodes and reports fail over to another node when the one
+	// that served the job is unreachable.
+	URLs     []string      `json:"-"`
 	Interval time.Duration `json:"-"`
-}
-
-// EnableLogging controls whether the package emits log output. Default true.
-var EnableLogging = true
-
-// SetEnableLogging sets the package logging flag.
-func SetEnableLogging(enabled bool) {
-	EnableLogging = enabled
+	// Concurrency is the number of jobs handled in parallel (default 1). With
+	// more than one, the handler must be safe for concurrent use.
+	Concurrency int `json:"-"`
 }
 
 // Acquire_jobs performs a POST to the acquire jobs endpoint (/acquire/jobs) with a JSON body.
+// A 429 or 503 response is returned as a *BackPressureError. When URLs is set,
+// the next node is tried if one is unreachable or answers with a 5xx status.
 func Acquire_jobs(reqBody AcquireRequest) (jobs []interface{}, body string, status int, err error) {
-	full := reqBody.URL + job_api + "/acquire/jobs"
+	jobs, body, status, _, err = acquireJobs(context.Background(), pinPool(reqBody), reqBody)
+	return jobs, body, status, err
+}
+
+// acquireJobs implements Acquire_jobs, bounded by ctx. It also returns the
+// base URL of the node that answered.
+func acquireJobs(ctx context.Context, nodes *endpointPool, reqBody AcquireRequest) (jobs []interface{}, body string, status int, node string, err error) {
+	ctx, span := startSpan(ctx, SpanAcquire)
+	span.SetAttribute(AttrTopic, reqBody.Topic)
+	defer func() {
+		span.SetAttribute("node", node)
+		span.SetAttribute("status", status)
+		span.SetAttribute("jobs", len(jobs))
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+	}()
 	payload, err := json.Marshal(reqBody)
 	if err != nil {
-		return nil, "", -1, err
+		return nil, "", -1, "", err
+	}
+	var bodyBytes []byte
+	order := nodes.acquireOrder()
+	for i, n := range order {
+		node = n
+		var full string
+		if full, err = endpointURL(node, nil, "acquire", "jobs"); err != nil {
+			return nil, "", -1, node, err
+		}
+		status, bodyBytes, err = restPost(ctx, callAcquire, full, payload)
+		if !nodeFailed(ctx, status, err) {
+			nodes.markUp(node)
+			break
+		}
+		nodes.markDown(node)
+		if i < len(order)-1 {
+			logAt(ctx, ComponentAcquire, slog.LevelWarn, "node failed, trying next node", slog.String("node", node), slog.Int("status", status), slog.Any("error", err))
+		}
 	}
-	status, bodyBytes, err := restPost(full, payload)
 	if err != nil {
-		return nil, "", status, err
+		return nil, "", status, node, err
 	}
 
+	// Keep numbers as json.Number, so that ids and long values above 2^53
+	// are not rounded.
 	var parsed []interface{}
-	if err := json.Unmarshal(bodyBytes, &parsed); err != nil {
+	if err := decodeNumbers(bodyBytes, &parsed); err != nil {
 		// If response isn't a JSON array, return an error
-		return nil, string(bodyBytes), status, err
+		return nil, string(bodyBytes), status, node, err
 	}
-	return parsed, string(bodyBytes), status, nil
+	return parsed, string(bodyBytes), status, node, nil
 }
 
 // List_jobs performs a single GET to the jobs endpoint and returns the status and raw response body.
 func List_jobs(url string) (status int, body string, err error) {
-	full := url + job_api + "/jobs"
-	status, bodyBytes, err := restGet(full)
+	return List_jobs_filtered(url, JobFilter{})
+}
+
+// List_jobs_filtered is like List_jobs but sends filter as query parameters.
+func List_jobs_filtered(url string, filter JobFilter) (status int, body string, err error) {
+	full, err := endpointURL(url, filter.Values(), "jobs")
 	if err != nil {
 		return -1, "", err
 	}
-	return status, string(bodyBytes), nil
+	status, bodyBytes, err := restGet(context.Background(), callQuery, full)
+	return status, string(bodyBytes), err
 }
 
-// Subscribe polls the given URL at intervals and invokes the handler when jobs are available.
-// acquireReq must be provided by the caller with the desired acquire parameters.
-func Subscribe(acquireReq AcquireRequest, handler ResponseHandler) {
-	for {
-		jobs, _, status, err := Acquire_jobs(acquireReq)
-		if err != nil {
-			// If acquire failed (including parse errors), treat as status 500 and pass the raw body if available
-			resStatus, resObj := handler(500, "")
-			handle_worker_response(acquireReq.URL, acquireReq.WorkerId, "", resStatus, resObj)
-			time.Sleep(acquireReq.Interval)
-			continue
-		}
-		if len(jobs) == 0 {
-			// No jobs, wait and poll again
-			time.Sleep(acquireReq.Interval)
-			continue
-		}
-		// Jobs found, invoke handler for each job individually
-		for _, job := range jobs {
-			jobBytes, err := json.Marshal(job)
-			if err != nil {
-				// If we can't serialize an individual job, treat as processing/parsing failure => status 500
-				resStatus, resObj := handler(500, "")
-				handle_worker_response(acquireReq.URL, acquireReq.WorkerId, "", resStatus, resObj)
-				continue
-			}
-			// Try to extract a jobId if present in the job object
-			jobId := ""
-			var jobMap map[string]interface{}
-			if err := json.Unmarshal(jobBytes, &jobMap); err == nil {
-				if id, ok := jobMap["id"].(string); ok && id != "" {
-					jobId = id
-				} else if jid, ok := jobMap["jobId"].(string); ok && jid != "" {
-					jobId = jid
-				} else if idnum, ok := jobMap["id"].(float64); ok {
-					jobId = fmt.Sprintf("%.0f", idnum)
-				}
-			}
-			resStatus, resObj := handler(status, string(jobBytes))
-			// Delegate result handling to helper
-			handle_worker_response(acquireReq.URL, acquireReq.WorkerId, jobId, resStatus, resObj)
-		}
-		time.Sleep(acquireReq.Interval)
-	}
+// Get_job performs a GET to the job endpoint (/jobs/{jobId}) and returns the status and raw response body.
+func Get_job(url string, jobId string) (status int, body string, err error) {
+	return getEndpoint(url, "jobs", jobId)
 }
 
-// handle_worker_response centralizes logging/processing of handler responses.
-// It also calls the appropriate task action (complete/fail/bpmnError/cmmnTerminate) via REST.
-func handle_worker_response(baseURL string, workerId string, jobId string, resStatus HandlerStatus, resObj *HandlerResult) {
-	// Ensure resObj has workerId populated
-	if resObj != nil && resObj.WorkerId == "" {
-		resObj.WorkerId = workerId
-	}
-
-	// Ensure we have a non-nil result object to send (create a minimal one if needed)
-	if resObj == nil {
-		resObj = &HandlerResult{WorkerId: workerId}
-	}
+// Get_job_stacktrace performs a GET to the exception stacktrace endpoint of a
+// job (/jobs/{jobId}/exception-stacktrace) and returns the status and the
+// stacktrace as plain text.
+func Get_job_stacktrace(url string, jobId string) (status int, body string, err error) {
+	return getEndpoint(url, "jobs", jobId, "exception-stacktrace")
+}
 
-	switch resStatus {
-	case HandlerSuccess:
-		task_complete(baseURL, jobId, resObj)
-	case HandlerFail:
-		if resObj.ErrorCode == "" {
-			resObj.ErrorCode = "failed"
-		}
-		task_fail(baseURL, jobId, resObj)
-	case HandlerBPMNError:
-		if resObj.ErrorCode == "" {
-			resObj.ErrorCode = "bpmnError"
-		}
-		task_bpmnError(baseURL, jobId, resObj)
-	case HandlerCMMNTerminate:
-		if resObj.ErrorCode == "" {
-			resObj.ErrorCode = "cmmnTerminate"
-		}
-		task_cmmnTerminate(baseURL, jobId, resObj)
-	default:
-		if EnableLogging {
-			log.Printf("Unhandled handler status: %s", resStatus)
-		}
+// getEndpoint performs a single GET to the endpoint below url made of segments.
+func getEndpoint(url string, segments ...string) (status int, body string, err error) {
+	full, err := endpointURL(url, nil, segments...)
+	if err != nil {
+		return -1, "", err
 	}
+	status, bodyBytes, err := restGet(context.Background(), callQuery, full)
+	return status, string(bodyBytes), err
 }
 
-// task_complete posts completion with workerId and result to the job-specific URL
-func task_complete(baseURL string, jobId string, res *HandlerResult) {
-	if jobId == "" {
-		if EnableLogging {
-			log.Printf("task_complete: missing jobId, skipping")
-		}
-		return
-	}
-	path := baseURL + job_api + "/acquire/jobs/" + jobId + "/complete"
-	b, err := json.Marshal(res)
+// Report_job posts res to the endpoint of resStatus (/acquire/jobs/{jobId}/complete,
+// fail, bpmnError or cmmnTerminate) and returns the status and raw response body,
+// e.g. to finish a job by hand. Unlike the reports of a subscription it is sent
+// once, to url only, and the outcome is returned instead of logged.
+func Report_job(url string, jobId string, resStatus HandlerStatus, res *HandlerResult) (status int, body string, err error) {
+	action, report, err := reportPayload("", resStatus, res)
 	if err != nil {
-		if EnableLogging {
-			log.Printf("task_complete: marshal error: %v", err)
-		}
-		return
+		return -1, "", err
 	}
-	status, body, err := restPost(path, b)
+	payload, err := json.Marshal(report)
 	if err != nil {
-		if EnableLogging {
-			log.Printf("task_complete: post error: %v", err)
-		}
-		return
+		return -1, "", err
 	}
-	if EnableLogging {
-		log.Printf("task_complete: status=%d, body=%s", status, string(body))
+	full, err := endpointURL(url, nil, "acquire", "jobs", jobId, action)
+	if err != nil {
+		return -1, "", err
 	}
+	status, bodyBytes, err := restPost(context.Background(), callReport, full, payload)
+	return status, string(bodyBytes), err
 }
 
-// task_fail posts failure with workerId and result to the job-specific URL
-func task_fail(baseURL string, jobId string, res *HandlerResult) {
-	if jobId == "" {
-		if EnableLogging {
-			log.Printf("task_fail: missing jobId, skipping")
-		}
-		return
+// reportActions maps a HandlerStatus to the last path segment of its report endpoint.
+var reportActions = map[HandlerStatus]string{
+	HandlerSuccess:       "complete",
+	HandlerFail:          "fail",
+	HandlerBPMNError:     "bpmnError",
+	HandlerCMMNTerminate: "cmmnTerminate",
+}
+
+// reportErrorCodes are the error codes sent when a result of the status has none.
+var reportErrorCodes = map[HandlerStatus]string{
+	HandlerFail:          "failed",
+	HandlerBPMNError:     "bpmnError",
+	HandlerCMMNTerminate: "cmmnTerminate",
+}
+
+// reportPayload returns the report endpoint action for resStatus and the body
+// to post to it: a copy of res with the status, workerId and default error
+// code filled in. Report_job and the reports of subscriptions both use it,
+// so they send the same payload.
+func reportPayload(workerId string, resStatus HandlerStatus, res *HandlerResult) (string, HandlerResult, error) {
+	action, ok := reportActions[resStatus]
+	if !ok {
+		return "", HandlerResult{}, fmt.Errorf("unknown handler status %q", resStatus)
 	}
-	path := baseURL + job_api + "/acquire/jobs/" + jobId + "/fail"
-	b, err := json.Marshal(res)
+	report := HandlerResult{}
+	if res != nil {
+		report = *res
+	}
+	if report.Status == "" {
+		report.Status = resStatus
+	}
+	if report.WorkerId == "" {
+		report.WorkerId = workerId
+	}
+	if report.ErrorCode == "" {
+		report.ErrorCode = reportErrorCodes[resStatus]
+	}
+	return action, report, nil
+}
+
+// Subscribe polls the given URL at intervals and invokes the handler when jobs are available.
+// acquireReq must be provided by the caller with the desired acquire parameters.
+func Subscribe(acquireReq AcquireRequest, handler ResponseHandler) {
+	err := SubscribeContext(context.Background(), acquireReq, func(_ context.Context, status int, body string) (HandlerStatus, *HandlerResult) {
+		return handler(status, body)
+	})
 	if err != nil {
-		if EnableLogging {
-			log.Printf("task_fail: marshal error: %v", err)
-		}
-		return
+		logAt(context.Background(), ComponentSubscribe, slog.LevelError, "cannot subscribe", slog.String(AttrTopic, acquireReq.Topic), slog.Any("error", err))
 	}
-	status, body, err := restPost(path, b)
+}
+
+// SubscribeContext is like Subscribe but takes a ContextHandler and returns nil once ctx is done.
+// The context passed to the handler is derived from ctx. The subscription
+// runs on DefaultWorker, so it is covered by its health checks and Drain.
+// It returns an error right away when the subscription cannot start, e.g.
+// for a malformed URL or while DefaultWorker is draining.
+//
+// When the server answers an acquire with 429 or 503, the loop pauses for the
+// longer of its Retry-After and Interval without calling the handler. While
+// the circuit breaker is open, the loop waits for it to allow calls again.
+func SubscribeContext(ctx context.Context, acquireReq AcquireRequest, handler ContextHandler) error {
+	s, err := DefaultWorker.add(ctx, acquireReq, handler)
 	if err != nil {
-		if EnableLogging {
-			log.Printf("task_fail: post error: %v", err)
-		}
-		return
+		// Polling a malformed URL can never succeed; fail up front instead.
+		return fmt.Errorf("subscribe to topic %q: %w", acquireReq.Topic, err)
 	}
-	if EnableLogging {
-		log.Printf("task_fail: status=%d, body=%s", status, string(body))
+	s.run()
+	return nil
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first.
+// It reports whether the full duration elapsed.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
 	}
 }
 
-// task_bpmnError posts a BPMN error with workerId and result to the job-specific URL
-func task_bpmnError(baseURL string, jobId string, res *HandlerResult) {
-	if jobId == "" {
-		if EnableLogging {
-			log.Printf("task_bpmnError: missing jobId, skipping")
-		}
-		return
+// extractJobFields returns the "id" (or "jobId") and "processInstanceId"
+// fields of a raw job object; missing fields are returned empty.
+func extractJobFields(job []byte) (jobId, processInstanceId string) {
+	var jobMap map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(job))
+	dec.UseNumber()
+	if err := dec.Decode(&jobMap); err != nil {
+		return "", ""
 	}
-	path := baseURL + job_api + "/acquire/jobs/" + jobId + "/bpmnError"
-	b, err := json.Marshal(res)
-	if err != nil {
-		if EnableLogging {
-			log.Printf("task_bpmnError: marshal error: %v", err)
-		}
-		return
+	processInstanceId, _ = jobMap["processInstanceId"].(string)
+	if id, ok := jobMap["id"].(string); ok && id != "" {
+		return id, processInstanceId
+	} else if jid, ok := jobMap["jobId"].(string); ok && jid != "" {
+		return jid, processInstanceId
+	} else if idnum, ok := jobMap["id"].(json.Number); ok {
+		return idnum.String(), processInstanceId
+	}
+	return "", processInstanceId
+}
+
+// handle_worker_response centralizes logging/processing of handler responses.
+// It also calls the appropriate task action (complete/fail/bpmnError/cmmnTerminate) via REST,
+// preferably on baseURL, the node the job was acquired from. Retries of the
+// report stop at lockExpiry, or after MaxReportCircuitWait when it is zero.
+func handle_worker_response(ctx context.Context, nodes *endpointPool, baseURL string, workerId string, jobId string, resStatus HandlerStatus, resObj *HandlerResult, lockExpiry time.Time) {
+	// Report even if the subscription is being cancelled, so finished work is
+	// not lost, but not beyond the lock: once it expires the server releases
+	// the job, and retrying longer would only hold up a drain.
+	if lockExpiry.IsZero() {
+		lockExpiry = time.Now().Add(MaxReportCircuitWait)
 	}
-	status, body, err := restPost(path, b)
+	ctx, cancel := context.WithDeadline(context.WithoutCancel(ctx), lockExpiry)
+	defer cancel()
+
+	action, report, err := reportPayload(workerId, resStatus, resObj)
 	if err != nil {
-		if EnableLogging {
-			log.Printf("task_bpmnError: post error: %v", err)
-		}
+		logAt(ctx, ComponentHandler, slog.LevelError, "unhandled handler status", slog.String(AttrOutcome, string(resStatus)))
 		return
 	}
-	if EnableLogging {
-		log.Printf("task_bpmnError: status=%d, body=%s", status, string(body))
-	}
+	task_report(ctx, nodes, baseURL, jobId, action, &report)
 }
 
-// task_cmmnTerminate posts a CMMN terminate with workerId and result to the job-specific URL
-func task_cmmnTerminate(baseURL string, jobId string, res *HandlerResult) {
+// task_report posts res to the action endpoint of the job and logs the outcome.
+func task_report(ctx context.Context, nodes *endpointPool, baseURL string, jobId string, action string, res *HandlerResult) {
+	ctx, span := startSpan(ctx, SpanReport)
+	defer span.End()
+	span.SetAttribute(AttrJobId, jobId)
+	span.SetAttribute(AttrOutcome, action)
+	outcome := slog.String(AttrOutcome, action)
 	if jobId == "" {
-		if EnableLogging {
-			log.Printf("task_cmmnTerminate: missing jobId, skipping")
-		}
+		logAt(ctx, ComponentReport, slog.LevelDebug, "missing jobId, skipping report", outcome)
 		return
 	}
-	path := baseURL + job_api + "/acquire/jobs/" + jobId + "/cmmnTerminate"
 	b, err := json.Marshal(res)
 	if err != nil {
-		if EnableLogging {
-			log.Printf("task_cmmnTerminate: marshal error: %v", err)
-		}
+		logAt(ctx, ComponentReport, slog.LevelError, "cannot marshal result", outcome, slog.Any("error", err))
 		return
 	}
-	status, body, err := restPost(path, b)
+	start := time.Now()
+	status, body, err := reportWithFailover(ctx, nodes, baseURL, []string{"acquire", "jobs", jobId, action}, b)
+	topic := topicFromContext(ctx)
+	span.SetAttribute("status", status)
 	if err != nil {
-		if EnableLogging {
-			log.Printf("task_cmmnTerminate: post error: %v", err)
-		}
+		span.RecordError(err)
+		metricReportErrors.add(1, topic, action)
+		logAt(ctx, ComponentReport, slog.LevelError, "report failed", outcome, slog.Int("status", status), slog.Any("error", err), durationAttr(time.Since(start)))
 		return
 	}
-	if EnableLogging {
-		log.Printf("task_cmmnTerminate: status=%d, body=%s", status, string(body))
+	level := slog.LevelInfo
+	switch {
+	case status == http.StatusNotFound || status == http.StatusConflict:
+		// The lock expired and the job was released or acquired by another worker.
+		metricLockLost.add(1, topic)
+		metricReportErrors.add(1, topic, action)
+		level = slog.LevelWarn
+	case status >= 300:
+		metricReportErrors.add(1, topic, action)
+		level = slog.LevelWarn
+	default:
+		metricReported.add(1, topic, action)
+	}
+	if status >= 300 {
+		span.RecordError(fmt.Errorf("report rejected with status %d", status))
+	}
+	if logEnabled(ComponentReport, level) {
+		logAt(ctx, ComponentReport, level, "job reported", outcome, slog.Int("status", status), slog.String("body", redactForLog(body)), durationAttr(time.Since(start)))
 	}
 }
 
+// ErrDuplicateVariable is returned by ExtractVariablesFromBody when the same
+// variable name appears more than once in a job body.
+var ErrDuplicateVariable = errors.New("duplicate variable name")
+
+// ErrEmptyVariableName is returned by ExtractVariablesFromBody when a variable
+// has neither a name nor an id.
+var ErrEmptyVariableName = errors.New("empty variable name")
+
 // ExtractVariablesFromBody parses the job body JSON and attempts to extract
 // the "variables" element into a slice of HandlerVariable. Supports both
 // object (map) and array formats. Returns an empty slice if no variables
 // element is present.
+//
+// The order of the result is stable: array variables keep the order sent by
+// the server, object variables are sorted by name. Duplicate or empty variable
+// names are reported as ErrDuplicateVariable or ErrEmptyVariableName.
+//
+// Numbers are decoded without going through float64 first, so large values
+// are not corrupted: "integer", "short" and "long" variables become int64,
+// "double" variables become float64, "bigdecimal" and "biginteger" variables
+// stay json.Number, and numbers of any other type (including nested values of
+// "json" variables) become float64 only when that is lossless and json.Number
+// otherwise. All of these marshal back to the exact same JSON number.
 func ExtractVariablesFromBody(body string) ([]HandlerVariable, error) {
-	var data map[string]interface{}
+	var data map[string]json.RawMessage
 	if err := json.Unmarshal([]byte(body), &data); err != nil {
 		return nil, err
 	}
+	varsRaw, ok := data["variables"]
+	if !ok {
+		return nil, nil
+	}
 	var result []HandlerVariable
-	if varsRaw, ok := data["variables"]; ok {
-		switch vars := varsRaw.(type) {
-		case map[string]interface{}:
-			for name, v := range vars {
-				if vm, ok := v.(map[string]interface{}); ok {
-					varType := ""
-					if t, ok := vm["type"].(string); ok {
-						varType = t
-					}
-					varValue := vm["value"]
-					result = append(result, HandlerVariable{Name: name, Type: varType, Value: varValue})
-				} else {
-					result = append(result, HandlerVariable{Name: name, Type: "", Value: v})
+	switch firstByte(varsRaw) {
+	case '{':
+		names, values, err := decodeObjectInOrder(varsRaw)
+		if err != nil {
+			return nil, err
+		}
+		for i, name := range names {
+			if vm, ok := values[i].(map[string]interface{}); ok {
+				varType := ""
+				if t, ok := vm["type"].(string); ok {
+					varType = t
 				}
+				varValue := decodeVariableValue(varType, vm["value"])
+				result = append(result, HandlerVariable{Name: name, Type: varType, Value: varValue})
+			} else {
+				result = append(result, HandlerVariable{Name: name, Type: "", Value: decodeVariableValue("", values[i])})
 			}
-		case []interface{}:
-			for _, item := range vars {
-				if vm, ok := item.(map[string]interface{}); ok {
-					name, _ := vm["name"].(string)
-					varType, _ := vm["type"].(string)
-					varValue := vm["value"]
-					if name == "" {
-						if idstr, ok := vm["id"].(string); ok {
-							name = idstr
-						}
+		}
+		sort.SliceStable(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	case '[':
+		var vars []interface{}
+		if err := decodeNumbers(varsRaw, &vars); err != nil {
+			return nil, err
+		}
+		for _, item := range vars {
+			if vm, ok := item.(map[string]interface{}); ok {
+				name, _ := vm["name"].(string)
+				varType, _ := vm["type"].(string)
+				varValue := decodeVariableValue(varType, vm["value"])
+				if name == "" {
+					if idstr, ok := vm["id"].(string); ok {
+						name = idstr
 					}
-					result = append(result, HandlerVariable{Name: name, Type: varType, Value: varValue})
 				}
+				result = append(result, HandlerVariable{Name: name, Type: varType, Value: varValue})
 			}
-		default:
-			result = append(result, HandlerVariable{Name: "variables", Type: "json", Value: varsRaw})
 		}
+	default:
+		var v interface{}
+		if err := decodeNumbers(varsRaw, &v); err != nil {
+			return nil, err
+		}
+		result = append(result, HandlerVariable{Name: "variables", Type: "json", Value: decodeVariableValue("json", v)})
+	}
+	seen := make(map[string]bool, len(result))
+	for i, v := range result {
+		if v.Name == "" {
+			return nil, fmt.Errorf("%w at position %d", ErrEmptyVariableName, i)
+		}
+		if seen[v.Name] {
+			return nil, fmt.Errorf("%w: %q", ErrDuplicateVariable, v.Name)
+		}
+		seen[v.Name] = true
 	}
 	return result, nil
 }
 
+// decodeNumbers unmarshals raw into v, keeping numbers as json.Number.
+func decodeNumbers(raw []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// firstByte returns the first non-whitespace byte of raw, or 0 if there is none.
+func firstByte(raw []byte) byte {
+	trimmed := bytes.TrimLeft(raw, " \t\r\n")
+	if len(trimmed) == 0 {
+		return 0
+	}
+	return trimmed[0]
+}
+
+// decodeObjectInOrder decodes a JSON object token by token so that the keys
+// are returned in document order and repeated keys can be detected, which a
+// plain unmarshal into a map would silently collapse.
+func decodeObjectInOrder(raw []byte) (keys []string, values []interface{}, err error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if _, err := dec.Token(); err != nil {
+		return nil, nil, err
+	}
+	seen := make(map[string]bool)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		key := tok.(string)
+		if seen[key] {
+			return nil, nil, fmt.Errorf("%w: %q", ErrDuplicateVariable, key)
+		}
+		seen[key] = true
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, key)
+		values = append(values, v)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, nil, err
+	}
+	return keys, values, nil
+}
+
+// decodeVariableValue converts the json.Number values produced by a UseNumber
+// decoder into the Go type that matches the Flowable variable type.
+func decodeVariableValue(varType string, v interface{}) interface{} {
+	switch val := v.(type) {
+	case json.Number:
+		switch varType {
+		case "integer", "short", "long":
+			if i, err := val.Int64(); err == nil {
+				return i
+			}
+			return val
+		case "double":
+			if f, err := val.Float64(); err == nil {
+				return f
+			}
+			return val
+		case "bigdecimal", "biginteger":
+			return val
+		default:
+			return losslessNumber(val)
+		}
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = decodeVariableValue("", item)
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = decodeVariableValue("", item)
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+// losslessNumber returns n as a float64 when formatting that float64 yields
+// the same number again, and n unchanged otherwise.
+func losslessNumber(n json.Number) interface{} {
+	f, err := n.Float64()
+	if err != nil {
+		return n
+	}
+	if i, err := n.Int64(); err == nil {
+		if int64(f) == i && i <= maxSafeInteger && i >= -maxSafeInteger {
+			return f
+		}
+		return n
+	}
+	if strconv.FormatFloat(f, 'g', -1, 64) == strings.ToLower(n.String()) {
+		return f
+	}
+	return n
+}
+
+// maxSafeInteger is the largest integer a float64 represents exactly (2^53).
+const maxSafeInteger = 1 << 53
+
 // GetVar returns the value of the variable named `name` from the provided
 // slice of HandlerVariable as a string. It handles common JSON types (string,
 // number, boolean, and complex types marshaled to JSON). If the variable is
@@ -348,6 +572,10 @@ func GetVar(vars []HandlerVariable, name string) string {
 				return fmt.Sprintf("%.0f", val)
 			}
 			return fmt.Sprintf("%v", val)
+		case int64:
+			return strconv.FormatInt(val, 10)
+		case json.Number:
+			return val.String()
 		case bool:
 			return fmt.Sprintf("%v", val)
 		default:
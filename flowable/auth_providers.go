@@ -0,0 +1,229 @@
+package flowable
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// auth_providers.go contains the built-in Authenticator implementations besides OAuth2.
+
+// BasicAuthenticator sends fixed basic auth credentials.
+type BasicAuthenticator struct {
+	Username string
+	Password string
+}
+
+// Authenticate sets basic auth on req.
+func (a BasicAuthenticator) Authenticate(req *http.Request) error {
+	req.SetBasicAuth(a.Username, a.Password)
+	return nil
+}
+
+// BearerAuthenticator sends a fixed bearer token.
+type BearerAuthenticator struct {
+	Token string
+}
+
+// Authenticate sets the Authorization header on req.
+func (a BearerAuthenticator) Authenticate(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+a.Token)
+	return nil
+}
+
+// HeaderAuthenticator sends a credential in a custom header, e.g. an API key.
+type HeaderAuthenticator struct {
+	Header string
+	// Prefix is prepended to Value, e.g. "ApiKey ".
+	Prefix string
+	Value  string
+}
+
+// NewAPIKeyAuthenticator returns a HeaderAuthenticator sending key in header.
+func NewAPIKeyAuthenticator(header, key string) HeaderAuthenticator {
+	return HeaderAuthenticator{Header: header, Value: key}
+}
+
+// Authenticate sets the configured header on req.
+func (a HeaderAuthenticator) Authenticate(req *http.Request) error {
+	if a.Header == "" {
+		return errors.New("header authenticator: no header name configured")
+	}
+	req.Header.Set(a.Header, a.Prefix+a.Value)
+	return nil
+}
+
+// Credentials is a set of credentials read from an external source. When
+// Token is set it is sent as a bearer token, otherwise Username/Password are
+// sent as basic auth.
+type Credentials struct {
+	Username string
+	Password string
+	Token    string
+}
+
+// apply adds c to req.
+func (c Credentials) apply(req *http.Request) error {
+	switch {
+	case c.Token != "":
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	case c.Username != "" || c.Password != "":
+		req.SetBasicAuth(c.Username, c.Password)
+	default:
+		return errors.New("no credentials available")
+	}
+	return nil
+}
+
+// EnvAuthenticator reads credentials from environment variables on every
+// request, so changes to the environment take effect immediately. Empty
+// variable names fall back to FLOWABLE_TOKEN, FLOWABLE_USERNAME and FLOWABLE_PASSWORD.
+type EnvAuthenticator struct {
+	TokenVar    string
+	UsernameVar string
+	PasswordVar string
+}
+
+// Authenticate adds the credentials currently found in the environment to req.
+func (a EnvAuthenticator) Authenticate(req *http.Request) error {
+	c := Credentials{
+		Token:    os.Getenv(orDefault(a.TokenVar, "FLOWABLE_TOKEN")),
+		Username: os.Getenv(orDefault(a.UsernameVar, "FLOWABLE_USERNAME")),
+		Password: os.Getenv(orDefault(a.PasswordVar, "FLOWABLE_PASSWORD")),
+	}
+	if err := c.apply(req); err != nil {
+		return fmt.Errorf("env authenticator: %w", err)
+	}
+	return nil
+}
+
+// FileAuthenticator reads credentials from files, such as the keys of a
+// mounted Kubernetes secret. The files are re-read whenever their size or
+// modification time changes (checked at most every CheckInterval) and after
+// the server rejects a request with 401, so rotated secrets are picked up
+// without a restart. Set TokenFile, or UsernameFile and PasswordFile.
+type FileAuthenticator struct {
+	TokenFile    string
+	UsernameFile string
+	PasswordFile string
+	// CheckInterval defaults to one second.
+	CheckInterval time.Duration
+
+	mu        sync.Mutex
+	creds     Credentials
+	stamps    map[string]fileStamp
+	lastCheck time.Time
+}
+
+// fileStamp identifies a version of a file.
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// NewSecretDirAuthenticator returns a FileAuthenticator for a mounted secret
+// directory holding either a "token" key or "username" and "password" keys.
+func NewSecretDirAuthenticator(dir string) *FileAuthenticator {
+	dir = strings.TrimSuffix(dir, "/")
+	if _, err := os.Stat(dir + "/token"); err == nil {
+		return &FileAuthenticator{TokenFile: dir + "/token"}
+	}
+	return &FileAuthenticator{UsernameFile: dir + "/username", PasswordFile: dir + "/password"}
+}
+
+// Authenticate adds the current file credentials to req.
+func (a *FileAuthenticator) Authenticate(req *http.Request) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	interval := a.CheckInterval
+	if interval == 0 {
+		interval = time.Second
+	}
+	if a.stamps == nil || time.Since(a.lastCheck) >= interval {
+		if err := a.reload(); err != nil {
+			return err
+		}
+	}
+	if err := a.creds.apply(req); err != nil {
+		return fmt.Errorf("file authenticator: %w", err)
+	}
+	return nil
+}
+
+// Invalidate forces the files to be re-read on the next request.
+func (a *FileAuthenticator) Invalidate() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.stamps = nil
+}
+
+// reload re-reads the credential files if any of them changed. While a file
+// is missing, empty or half-written, as during a rotation, the last good
+// credentials stay in use and the files are read again on the next check.
+func (a *FileAuthenticator) reload() error {
+	a.lastCheck = time.Now()
+	err := a.readFiles()
+	if err != nil && a.creds != (Credentials{}) {
+		logAt(context.Background(), ComponentAuth, slog.LevelWarn, "cannot read credential files, keeping the previous credentials", slog.Any("error", err))
+		return nil
+	}
+	return err
+}
+
+// readFiles reads the credential files if any of them changed since the last
+// successful read.
+func (a *FileAuthenticator) readFiles() error {
+	files := []string{a.TokenFile, a.UsernameFile, a.PasswordFile}
+	stamps := make(map[string]fileStamp, len(files))
+	changed := a.stamps == nil
+	for _, f := range files {
+		if f == "" {
+			continue
+		}
+		fi, err := os.Stat(f)
+		if err != nil {
+			return fmt.Errorf("file authenticator: %w", err)
+		}
+		stamps[f] = fileStamp{size: fi.Size(), modTime: fi.ModTime()}
+		if stamps[f] != a.stamps[f] {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	var creds Credentials
+	for _, item := range []struct {
+		path string
+		dst  *string
+	}{{a.TokenFile, &creds.Token}, {a.UsernameFile, &creds.Username}, {a.PasswordFile, &creds.Password}} {
+		if item.path == "" {
+			continue
+		}
+		b, err := os.ReadFile(item.path)
+		if err != nil {
+			return fmt.Errorf("file authenticator: %w", err)
+		}
+		*item.dst = strings.TrimRight(string(b), "\r\n")
+		if *item.dst == "" {
+			return fmt.Errorf("file authenticator: %s is empty", item.path)
+		}
+	}
+	a.creds = creds
+	a.stamps = stamps
+	return nil
+}
+
+// orDefault returns s, or def when s is empty.
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,142 @@
+package flowable
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backpressure.go handles 429/503 responses: parsing Retry-After, pausing the
+// acquire loop and retrying reports once the server is ready again.
+
+// MaxRetryAfter caps the pause requested by a Retry-After header.
+const MaxRetryAfter = 10 * time.Minute
+
+// ReportRetryAttempts is how many times a report (complete, fail, ...) is sent
+// in total while the server keeps answering 429/503.
+const ReportRetryAttempts = 5
+
+// MaxReportCircuitWait bounds how long a report waits for an open circuit breaker.
+const MaxReportCircuitWait = 10 * time.Minute
+
+// defaultReportBackoff is used between report attempts when the server sends no Retry-After.
+const defaultReportBackoff = time.Second
+
+// BackPressureError is returned for 429 Too Many Requests and 503 Service
+// Unavailable responses. RetryAfter is the pause requested by the server, or
+// zero when it sent no (valid) Retry-After header.
+type BackPressureError struct {
+	Status     int
+	RetryAfter time.Duration
+}
+
+func (e *BackPressureError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("server applied back-pressure (status %d), retry after %s", e.Status, e.RetryAfter)
+	}
+	return fmt.Sprintf("server applied back-pressure (status %d)", e.Status)
+}
+
+// BackPressureEvent describes a pause caused by server back-pressure.
+type BackPressureEvent struct {
+	// Operation is "acquire" or "report".
+	Operation string
+	URL       string
+	Status    int
+	// Pause is how long the operation waits before trying again.
+	Pause time.Duration
+}
+
+var (
+	backPressureMu   sync.RWMutex
+	backPressureHook func(BackPressureEvent)
+)
+
+// SetBackPressureHook registers fn to be called whenever an acquire loop or a
+// report pauses because of server back-pressure. Pass nil to remove it.
+func SetBackPressureHook(fn func(BackPressureEvent)) {
+	backPressureMu.Lock()
+	defer backPressureMu.Unlock()
+	backPressureHook = fn
+}
+
+// notifyBackPressure logs ev under the component of its operation and passes
+// it to the registered hook.
+func notifyBackPressure(ctx context.Context, ev BackPressureEvent) {
+	component := ComponentAcquire
+	if ev.Operation == "report" {
+		component = ComponentReport
+	}
+	logAt(ctx, component, slog.LevelWarn, "back-pressure from server, pausing", slog.String("operation", ev.Operation), slog.String("url", ev.URL), slog.Int("status", ev.Status), slog.Duration("pause", ev.Pause))
+	backPressureMu.RLock()
+	fn := backPressureHook
+	backPressureMu.RUnlock()
+	if fn != nil {
+		fn(ev)
+	}
+}
+
+// backPressureFromResponse returns a *BackPressureError for 429 and 503 responses, nil otherwise.
+func backPressureFromResponse(resp *http.Response) error {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return nil
+	}
+	return &BackPressureError{Status: resp.StatusCode, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
+}
+
+// parseRetryAfter parses a Retry-After value given either as delay-seconds or
+// as an HTTP-date. Invalid or past values yield zero; the result is capped at MaxRetryAfter.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	var d time.Duration
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if secs <= 0 {
+			return 0
+		}
+		if secs > int64(MaxRetryAfter/time.Second) {
+			return MaxRetryAfter
+		}
+		d = time.Duration(secs) * time.Second
+	} else if t, err := http.ParseTime(v); err == nil {
+		d = t.Sub(now)
+	}
+	if d <= 0 {
+		return 0
+	}
+	if d > MaxRetryAfter {
+		return MaxRetryAfter
+	}
+	return d
+}
+
+// reportWithRetry posts a report and, while the server answers with
+// back-pressure, waits as requested and tries again up to ReportRetryAttempts
+// times. The whole sequence stops at the deadline of ctx, which the caller
+// sets to the lock expiry of the job.
+func reportWithRetry(ctx context.Context, path string, payload []byte) (status int, body []byte, err error) {
+	for attempt := 1; ; attempt++ {
+		status, body, err = restPost(ctx, callReport, path, payload)
+		var bp *BackPressureError
+		if !errors.As(err, &bp) || attempt >= ReportRetryAttempts {
+			return status, body, err
+		}
+		pause := bp.RetryAfter
+		if pause == 0 {
+			pause = defaultReportBackoff
+		}
+		notifyBackPressure(ctx, BackPressureEvent{Operation: "report", URL: path, Status: bp.Status, Pause: pause})
+		if !sleepContext(ctx, pause) {
+			return status, body, ctx.Err()
+		}
+		metricReportRetries.add(1, "back-pressure")
+	}
+}
@@ -0,0 +1,315 @@
+package flowable
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// circuit_breaker.go stops hammering an unreachable Flowable: after repeated
+// failures REST calls fail fast with ErrCircuitOpen until a cool-down has
+// passed, then a limited number of probe calls decide whether to close again.
+
+// ErrCircuitOpen is returned by REST calls while the circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState is the state of a CircuitBreaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets all calls through.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects all calls until the cool-down has passed.
+	CircuitOpen
+	// CircuitHalfOpen lets a limited number of probe calls through.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerOptions configures a CircuitBreaker. Zero values select the defaults.
+type CircuitBreakerOptions struct {
+	// FailureThreshold is the number of consecutive failures that opens the circuit (default 5).
+	FailureThreshold int
+	// CoolDown is how long the circuit stays open before probing (default 30 seconds).
+	CoolDown time.Duration
+	// HalfOpenProbes is the number of concurrent probe calls allowed while half-open (default 1).
+	HalfOpenProbes int
+	// OnStateChange is called after every state change of a node, with the
+	// scheme and host of the node.
+	OnStateChange func(node string, from, to CircuitState)
+}
+
+// CircuitBreaker tracks the health of every Flowable node (scheme and host)
+// separately, so one node that is down does not block calls to the others.
+// Network errors and 5xx responses count as failures; any other response
+// counts as success. Requests that cannot be built or authenticated are not
+// counted.
+type CircuitBreaker struct {
+	opts CircuitBreakerOptions
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+	changed  chan struct{}
+}
+
+// circuit is the state of one node.
+type circuit struct {
+	state    CircuitState
+	failures int
+	probes   int
+	openedAt time.Time
+}
+
+// NewCircuitBreaker returns a closed CircuitBreaker.
+func NewCircuitBreaker(opts CircuitBreakerOptions) *CircuitBreaker {
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = 5
+	}
+	if opts.CoolDown <= 0 {
+		opts.CoolDown = 30 * time.Second
+	}
+	if opts.HalfOpenProbes <= 0 {
+		opts.HalfOpenProbes = 1
+	}
+	return &CircuitBreaker{opts: opts, circuits: make(map[string]*circuit), changed: make(chan struct{})}
+}
+
+// State returns the combined state of the nodes: closed while any node is
+// closed (or none was called yet), half-open while any node is probed, and
+// open when every node is open.
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if len(cb.circuits) == 0 {
+		return CircuitClosed
+	}
+	state := CircuitOpen
+	for _, c := range cb.circuits {
+		switch c.state {
+		case CircuitClosed:
+			return CircuitClosed
+		case CircuitHalfOpen:
+			state = CircuitHalfOpen
+		}
+	}
+	return state
+}
+
+// NodeState returns the state of the node of baseURL.
+func (cb *CircuitBreaker) NodeState(baseURL string) CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if c, ok := cb.circuits[circuitKey(baseURL)]; ok {
+		return c.state
+	}
+	return CircuitClosed
+}
+
+// circuitKey returns the node of url: its scheme and host.
+func circuitKey(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// circuit returns the circuit of node, creating it closed. cb.mu must be held.
+func (cb *CircuitBreaker) circuit(node string) *circuit {
+	c, ok := cb.circuits[node]
+	if !ok {
+		c = &circuit{}
+		cb.circuits[node] = c
+	}
+	return c
+}
+
+// allow reports whether a call to node may be made now. Every nil return
+// must be followed by exactly one call to record for the same node.
+func (cb *CircuitBreaker) allow(node string) error {
+	cb.mu.Lock()
+	c := cb.circuit(node)
+	switch c.state {
+	case CircuitOpen:
+		if time.Since(c.openedAt) < cb.opts.CoolDown {
+			cb.mu.Unlock()
+			return ErrCircuitOpen
+		}
+		cb.setState(c, CircuitHalfOpen)
+		c.probes = 1
+		cb.mu.Unlock()
+		cb.notify(node, CircuitOpen, CircuitHalfOpen)
+		return nil
+	case CircuitHalfOpen:
+		defer cb.mu.Unlock()
+		if c.probes >= cb.opts.HalfOpenProbes {
+			return ErrCircuitOpen
+		}
+		c.probes++
+		return nil
+	default:
+		cb.mu.Unlock()
+		return nil
+	}
+}
+
+// callOutcome classifies a finished call for the circuit breaker.
+type callOutcome int
+
+const (
+	outcomeSuccess callOutcome = iota
+	outcomeFailure
+	// outcomeIgnored releases a probe without judging the endpoint, e.g. when the caller cancelled.
+	outcomeIgnored
+)
+
+// record reports the outcome of a call to node allowed by allow.
+func (cb *CircuitBreaker) record(node string, outcome callOutcome) {
+	cb.mu.Lock()
+	c := cb.circuit(node)
+	from := c.state
+	switch {
+	case outcome == outcomeIgnored:
+		if c.state == CircuitHalfOpen && c.probes > 0 {
+			c.probes--
+			cb.broadcast()
+		}
+	case outcome == outcomeSuccess:
+		c.failures = 0
+		if c.state == CircuitHalfOpen {
+			cb.setState(c, CircuitClosed)
+		}
+	case c.state == CircuitHalfOpen:
+		c.openedAt = time.Now()
+		cb.setState(c, CircuitOpen)
+	default:
+		c.failures++
+		if c.state == CircuitClosed && c.failures >= cb.opts.FailureThreshold {
+			c.openedAt = time.Now()
+			cb.setState(c, CircuitOpen)
+		}
+	}
+	to := c.state
+	cb.mu.Unlock()
+	if from != to {
+		cb.notify(node, from, to)
+	}
+}
+
+// Wait blocks until a call may be attempted again on at least one node:
+// immediately while a node is closed, otherwise until the cool-down of a node
+// has passed, a probe slot of a half-open node is free or a state changes.
+// It returns ctx.Err() if ctx ends first.
+func (cb *CircuitBreaker) Wait(ctx context.Context) error {
+	for {
+		cb.mu.Lock()
+		changed := cb.changed
+		wait, ready := cb.nextAttempt()
+		cb.mu.Unlock()
+		if ready {
+			return nil
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-changed:
+			t.Stop()
+		case <-t.C:
+		}
+	}
+}
+
+// nextAttempt reports whether a node may be called now and, if not, how long
+// until one might. cb.mu must be held.
+func (cb *CircuitBreaker) nextAttempt() (time.Duration, bool) {
+	wait := cb.opts.CoolDown
+	for _, c := range cb.circuits {
+		switch c.state {
+		case CircuitClosed:
+			return 0, true
+		case CircuitOpen:
+			left := cb.opts.CoolDown - time.Since(c.openedAt)
+			if left <= 0 {
+				return 0, true
+			}
+			wait = min(wait, left)
+		case CircuitHalfOpen:
+			if c.probes < cb.opts.HalfOpenProbes {
+				return 0, true
+			}
+		}
+	}
+	return wait, len(cb.circuits) == 0
+}
+
+// setState changes the state of c and wakes up waiters. cb.mu must be held.
+func (cb *CircuitBreaker) setState(c *circuit, s CircuitState) {
+	c.state = s
+	if s != CircuitHalfOpen {
+		c.probes = 0
+	}
+	if s == CircuitClosed {
+		c.failures = 0
+	}
+	cb.broadcast()
+}
+
+// broadcast wakes up the callers of Wait. cb.mu must be held.
+func (cb *CircuitBreaker) broadcast() {
+	close(cb.changed)
+	cb.changed = make(chan struct{})
+}
+
+// notify logs a state change of node and calls the OnStateChange hook.
+func (cb *CircuitBreaker) notify(node string, from, to CircuitState) {
+	logAt(context.Background(), ComponentCircuit, slog.LevelWarn, "circuit breaker state changed", slog.String("node", node), slog.String("from", from.String()), slog.String("to", to.String()))
+	if cb.opts.OnStateChange != nil {
+		cb.opts.OnStateChange(node, from, to)
+	}
+}
+
+var (
+	circuitMu      sync.RWMutex
+	circuitBreaker *CircuitBreaker
+)
+
+// SetCircuitBreaker installs cb around all REST calls. Pass nil to remove it.
+func SetCircuitBreaker(cb *CircuitBreaker) {
+	circuitMu.Lock()
+	defer circuitMu.Unlock()
+	circuitBreaker = cb
+}
+
+// currentCircuitBreaker returns the breaker set by SetCircuitBreaker, if any.
+func currentCircuitBreaker() *CircuitBreaker {
+	circuitMu.RLock()
+	defer circuitMu.RUnlock()
+	return circuitBreaker
+}
+
+// waitForCircuit blocks until the installed circuit breaker, if any, allows
+// calls again to at least one node.
+func waitForCircuit(ctx context.Context) error {
+	if cb := currentCircuitBreaker(); cb != nil {
+		return cb.Wait(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,511 @@
+package flowable
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v4"
+)
+
+// config.go loads the worker configuration (Flowable nodes, credentials, TLS
+// and the subscribed topics) from a YAML or JSON file and FLOWABLE_*
+// environment variables.
+
+// Built-in topic settings, used when neither the topic nor the defaults section sets a value.
+const (
+	DefaultLockDuration = "PT5M"
+	DefaultBatchSize    = 1
+	DefaultConcurrency  = 1
+	DefaultInterval     = 10 * time.Second
+)
+
+// maskedSecret replaces passwords and tokens when a Config is printed.
+const maskedSecret = "****"
+
+// Config is the declarative configuration of a worker process.
+//
+//	urls: [https://flowable-1:8090, https://flowable-2:8090]
+//	workerId: orders-worker
+//	auth:
+//	  username: admin
+//	  password: test
+//	tls:
+//	  caFile: /etc/flowable/ca.pem
+//	defaults:
+//	  lockDuration: PT10M
+//	  interval: 10s
+//	topics:
+//	  - topic: orders
+//	    concurrency: 4
+//	  - topic: invoices
+//	    scopeType: cmmn
+//
+// Settings left out inherit from the defaults section, and from the built-in
+// defaults after that. A number set to 0, e.g. retries: 0, is kept.
+type Config struct {
+	// URLs are the base URLs of the Flowable nodes.
+	URLs []string `json:"urls" yaml:"urls"`
+	// APIPrefix overrides DefaultAPIPrefix.
+	APIPrefix string `json:"apiPrefix,omitempty" yaml:"apiPrefix,omitempty"`
+	// WorkerId defaults to the host name.
+	WorkerId string        `json:"workerId,omitempty" yaml:"workerId,omitempty"`
+	Auth     AuthConfig    `json:"auth" yaml:"auth"`
+	TLS      TLSConfig     `json:"tls" yaml:"tls"`
+	Defaults TopicConfig   `json:"defaults" yaml:"defaults"`
+	Topics   []TopicConfig `json:"topics" yaml:"topics"`
+}
+
+// AuthConfig holds either basic auth credentials or a bearer token.
+type AuthConfig struct {
+	Username string `json:"username,omitempty" yaml:"username,omitempty"`
+	Password string `json:"password,omitempty" yaml:"password,omitempty"`
+	Token    string `json:"token,omitempty" yaml:"token,omitempty"`
+}
+
+// TLSConfig is the file form of TLSOptions.
+type TLSConfig struct {
+	CAFile     string `json:"caFile,omitempty" yaml:"caFile,omitempty"`
+	CertFile   string `json:"certFile,omitempty" yaml:"certFile,omitempty"`
+	KeyFile    string `json:"keyFile,omitempty" yaml:"keyFile,omitempty"`
+	ServerName string `json:"serverName,omitempty" yaml:"serverName,omitempty"`
+}
+
+// TopicConfig holds the settings of one subscription. Topic is empty in the
+// defaults section.
+type TopicConfig struct {
+	Topic string `json:"topic,omitempty" yaml:"topic,omitempty"`
+	// LockDuration is an ISO-8601 duration such as "PT10M".
+	LockDuration string `json:"lockDuration,omitempty" yaml:"lockDuration,omitempty"`
+	// BatchSize is the number of jobs acquired per poll; nil inherits.
+	BatchSize *int `json:"batchSize,omitempty" yaml:"batchSize,omitempty"`
+	// Concurrency is the number of jobs handled in parallel; nil inherits.
+	Concurrency *int `json:"concurrency,omitempty" yaml:"concurrency,omitempty"`
+	// Interval is the pause between polls, e.g. "10s".
+	Interval Duration `json:"interval,omitempty" yaml:"interval,omitempty"`
+	// Retries is the number of retries of a failed job; nil inherits, so
+	// that a topic can set 0 over the defaults section.
+	Retries   *int   `json:"retries,omitempty" yaml:"retries,omitempty"`
+	ScopeType string `json:"scopeType,omitempty" yaml:"scopeType,omitempty"`
+}
+
+// Duration is a time.Duration written as a Go duration string such as "30s".
+type Duration time.Duration
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return fmt.Errorf("invalid duration %q (use e.g. \"10s\" or \"1m30s\")", b)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// ConfigError lists the problems found in a configuration.
+type ConfigError struct {
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// LoadConfig reads the configuration from path, applies the FLOWABLE_*
+// environment overrides and validates the result. An empty path loads the
+// configuration from the environment only. Files ending in .json are read as
+// JSON, anything else as YAML; unknown keys are rejected in both.
+//
+// The environment variables are:
+//
+//	FLOWABLE_URLS                comma-separated base URLs
+//	FLOWABLE_API_PREFIX          FLOWABLE_WORKER_ID
+//	FLOWABLE_USERNAME            FLOWABLE_PASSWORD            FLOWABLE_TOKEN
+//	FLOWABLE_TLS_CA_FILE         FLOWABLE_TLS_CERT_FILE       FLOWABLE_TLS_KEY_FILE
+//	FLOWABLE_TLS_SERVER_NAME
+//	FLOWABLE_TOPICS              comma-separated topics added to the file's topics
+//	FLOWABLE_LOCK_DURATION       FLOWABLE_BATCH_SIZE          FLOWABLE_CONCURRENCY
+//	FLOWABLE_INTERVAL            FLOWABLE_RETRIES             FLOWABLE_SCOPE_TYPE
+//
+// The last six override the defaults section. Prefixed with
+// FLOWABLE_TOPIC_<TOPIC>_ they override a single topic, with <TOPIC> in upper
+// case and other characters than letters and digits replaced by "_", e.g.
+// FLOWABLE_TOPIC_ORDER_EVENTS_CONCURRENCY for "order-events".
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+		if err := decodeConfig(b, strings.EqualFold(filepath.Ext(path), ".json"), cfg); err != nil {
+			return nil, fmt.Errorf("config: %s: %w", path, err)
+		}
+	}
+	var problems []string
+	cfg.applyEnv(os.LookupEnv, &problems)
+	problems = append(problems, cfg.problems()...)
+	if len(problems) > 0 {
+		return nil, &ConfigError{Problems: problems}
+	}
+	return cfg, nil
+}
+
+// decodeConfig decodes a JSON or YAML document into cfg, rejecting unknown keys.
+func decodeConfig(b []byte, isJSON bool, cfg *Config) error {
+	if isJSON {
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		return dec.Decode(cfg)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// applyEnv applies the FLOWABLE_* overrides read through lookup.
+func (c *Config) applyEnv(lookup func(string) (string, bool), problems *[]string) {
+	str := func(name string, dst *string) {
+		if v, ok := lookup(name); ok {
+			*dst = v
+		}
+	}
+	if v, ok := lookup("FLOWABLE_URLS"); ok {
+		c.URLs = splitList(v)
+	}
+	str("FLOWABLE_API_PREFIX", &c.APIPrefix)
+	str("FLOWABLE_WORKER_ID", &c.WorkerId)
+	str("FLOWABLE_USERNAME", &c.Auth.Username)
+	str("FLOWABLE_PASSWORD", &c.Auth.Password)
+	str("FLOWABLE_TOKEN", &c.Auth.Token)
+	str("FLOWABLE_TLS_CA_FILE", &c.TLS.CAFile)
+	str("FLOWABLE_TLS_CERT_FILE", &c.TLS.CertFile)
+	str("FLOWABLE_TLS_KEY_FILE", &c.TLS.KeyFile)
+	str("FLOWABLE_TLS_SERVER_NAME", &c.TLS.ServerName)
+	if v, ok := lookup("FLOWABLE_TOPICS"); ok {
+	topics:
+		for _, topic := range splitList(v) {
+			for _, t := range c.Topics {
+				if t.Topic == topic {
+					continue topics
+				}
+			}
+			c.Topics = append(c.Topics, TopicConfig{Topic: topic})
+		}
+	}
+	c.Defaults.applyEnv("FLOWABLE_", lookup, problems)
+	for i := range c.Topics {
+		c.Topics[i].applyEnv("FLOWABLE_TOPIC_"+envName(c.Topics[i].Topic)+"_", lookup, problems)
+	}
+}
+
+// applyEnv applies the topic settings found under prefix.
+func (t *TopicConfig) applyEnv(prefix string, lookup func(string) (string, bool), problems *[]string) {
+	num := func(name string, dst **int) {
+		if v, ok := lookup(prefix + name); ok {
+			n, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				*problems = append(*problems, fmt.Sprintf("%s%s: %q is not a whole number", prefix, name, v))
+				return
+			}
+			*dst = &n
+		}
+	}
+	if v, ok := lookup(prefix + "LOCK_DURATION"); ok {
+		t.LockDuration = v
+	}
+	num("BATCH_SIZE", &t.BatchSize)
+	num("CONCURRENCY", &t.Concurrency)
+	num("RETRIES", &t.Retries)
+	if v, ok := lookup(prefix + "INTERVAL"); ok {
+		if err := t.Interval.UnmarshalText([]byte(v)); err != nil {
+			*problems = append(*problems, fmt.Sprintf("%sINTERVAL: %v", prefix, err))
+		}
+	}
+	if v, ok := lookup(prefix + "SCOPE_TYPE"); ok {
+		t.ScopeType = v
+	}
+}
+
+// envName turns a topic into the form used in environment variable names.
+func envName(topic string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, topic)
+}
+
+// splitList splits a comma-separated list, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Validate reports all problems of c in a *ConfigError.
+func (c *Config) Validate() error {
+	if problems := c.problems(); len(problems) > 0 {
+		return &ConfigError{Problems: problems}
+	}
+	return nil
+}
+
+// problems returns a readable description of each problem of c.
+func (c *Config) problems() []string {
+	var p []string
+	if len(c.URLs) == 0 {
+		p = append(p, "urls: at least one Flowable base URL is required (or set FLOWABLE_URLS)")
+	}
+	for i, u := range c.URLs {
+		if err := ValidateBaseURL(u); err != nil {
+			p = append(p, fmt.Sprintf("urls[%d]: %v", i, err))
+		}
+	}
+	if c.APIPrefix != "" && !strings.HasPrefix(c.APIPrefix, "/") {
+		p = append(p, fmt.Sprintf("apiPrefix: %q must start with \"/\"", c.APIPrefix))
+	}
+	if c.Auth.Token != "" && (c.Auth.Username != "" || c.Auth.Password != "") {
+		p = append(p, "auth: set either username and password or token, not both")
+	}
+	if c.Auth.Password != "" && c.Auth.Username == "" {
+		p = append(p, "auth.username: required when a password is set")
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		p = append(p, "tls: certFile and keyFile must be set together")
+	}
+	p = append(p, c.Defaults.problems("defaults")...)
+	if len(c.Topics) == 0 {
+		p = append(p, "topics: at least one topic is required (or set FLOWABLE_TOPICS)")
+	}
+	seen := make(map[string]bool)
+	for i, t := range c.Topics {
+		field := fmt.Sprintf("topics[%d]", i)
+		switch {
+		case strings.TrimSpace(t.Topic) == "":
+			p = append(p, field+".topic: required")
+		case seen[t.Topic]:
+			p = append(p, fmt.Sprintf("%s.topic: %q is listed more than once", field, t.Topic))
+		}
+		seen[t.Topic] = true
+		p = append(p, t.problems(field)...)
+	}
+	return p
+}
+
+// problems validates the settings of a topic or of the defaults section.
+func (t TopicConfig) problems(field string) []string {
+	var p []string
+	if t.LockDuration != "" {
+		if d, err := parseISODuration(t.LockDuration); err != nil || d <= 0 {
+			p = append(p, fmt.Sprintf("%s.lockDuration: %q is not a positive ISO-8601 duration (e.g. PT10M)", field, t.LockDuration))
+		}
+	}
+	for _, n := range []struct {
+		name  string
+		value *int
+		min   int
+	}{{"batchSize", t.BatchSize, 1}, {"concurrency", t.Concurrency, 1}, {"retries", t.Retries, 0}} {
+		switch {
+		case n.value == nil:
+		case *n.value < 0:
+			p = append(p, fmt.Sprintf("%s.%s: must not be negative, got %d", field, n.name, *n.value))
+		case *n.value < n.min:
+			p = append(p, fmt.Sprintf("%s.%s: must be at least %d, got %d", field, n.name, n.min, *n.value))
+		}
+	}
+	if t.Interval < 0 {
+		p = append(p, fmt.Sprintf("%s.interval: must not be negative, got %s", field, time.Duration(t.Interval)))
+	}
+	switch strings.ToLower(t.ScopeType) {
+	case "", "bpmn", "cmmn":
+	default:
+		p = append(p, fmt.Sprintf("%s.scopeType: %q must be bpmn or cmmn", field, t.ScopeType))
+	}
+	return p
+}
+
+// Requests returns the acquire request of each topic with the defaults filled in.
+func (c *Config) Requests() []AcquireRequest {
+	workerId := c.WorkerId
+	if workerId == "" {
+		workerId, _ = os.Hostname()
+	}
+	var urls []string
+	if len(c.URLs) > 1 {
+		urls = c.URLs[1:]
+	}
+	out := make([]AcquireRequest, 0, len(c.Topics))
+	for _, t := range c.Topics {
+		t = t.withDefaults(c.Defaults)
+		req := AcquireRequest{
+			Topic:           t.Topic,
+			LockDuration:    t.LockDuration,
+			NumberOfTasks:   *t.BatchSize,
+			NumberOfRetries: *t.Retries,
+			WorkerId:        workerId,
+			ScopeType:       strings.ToLower(t.ScopeType),
+			URLs:            urls,
+			Interval:        time.Duration(t.Interval),
+			Concurrency:     *t.Concurrency,
+		}
+		if len(c.URLs) > 0 {
+			req.URL = c.URLs[0]
+		}
+		out = append(out, req)
+	}
+	return out
+}
+
+// withDefaults fills the settings left out in t from defaults and the
+// built-in defaults. The numbers of the result are never nil.
+func (t TopicConfig) withDefaults(defaults TopicConfig) TopicConfig {
+	str := func(v *string, d, builtin string) {
+		if *v == "" {
+			*v = d
+		}
+		if *v == "" {
+			*v = builtin
+		}
+	}
+	num := func(v **int, d *int, builtin int) {
+		if *v == nil {
+			*v = d
+		}
+		if *v == nil {
+			*v = &builtin
+		}
+	}
+	str(&t.LockDuration, defaults.LockDuration, DefaultLockDuration)
+	str(&t.ScopeType, defaults.ScopeType, "")
+	num(&t.BatchSize, defaults.BatchSize, DefaultBatchSize)
+	num(&t.Concurrency, defaults.Concurrency, DefaultConcurrency)
+	num(&t.Retries, defaults.Retries, 0)
+	if t.Interval == 0 {
+		t.Interval = defaults.Interval
+	}
+	if t.Interval == 0 {
+		t.Interval = Duration(DefaultInterval)
+	}
+	return t
+}
+
+// Apply installs the API prefix, credentials and TLS settings of c for all
+// REST requests. Credentials and TLS settings are left alone when c has none.
+// Since an Authenticator installed with SetAuthenticator replaces basic auth
+// and bearer tokens, Apply fails when c has credentials and one is set.
+func (c *Config) Apply() error {
+	return c.apply(&Config{})
+}
+
+// apply installs the settings of c that differ from prev. TLS is configured
+// first, so that nothing is changed when it fails.
+func (c *Config) apply(prev *Config) error {
+	if c.Auth != (AuthConfig{}) && currentAuthenticator() != nil {
+		return errors.New("auth: the credentials of the configuration would be ignored, since an Authenticator is set with SetAuthenticator")
+	}
+	if c.TLS != prev.TLS {
+		err := ConfigureTLS(TLSOptions{
+			CAFile:     c.TLS.CAFile,
+			CertFile:   c.TLS.CertFile,
+			KeyFile:    c.TLS.KeyFile,
+			ServerName: c.TLS.ServerName,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if c.APIPrefix != prev.APIPrefix {
+		SetAPIPrefix(c.APIPrefix)
+	}
+	if c.Auth != prev.Auth {
+		SetAuth(c.Auth.Username, c.Auth.Password)
+		SetBearerToken(c.Auth.Token)
+	}
+	return nil
+}
+
+// Subscribe subscribes w to every topic of c. handlers maps a topic to its
+// handler; the handler under "*" is used for topics without one. Nothing is
+// subscribed when a topic has no handler.
+func (c *Config) Subscribe(ctx context.Context, w *Worker, handlers map[string]ContextHandler) error {
+	reqs := c.Requests()
+	if err := checkHandlers(reqs, handlers); err != nil {
+		return err
+	}
+	for _, req := range reqs {
+		if _, err := w.Subscribe(ctx, req, handlerFor(handlers, req.Topic)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkHandlers fails when one of reqs has no handler.
+func checkHandlers(reqs []AcquireRequest, handlers map[string]ContextHandler) error {
+	for _, req := range reqs {
+		if handlerFor(handlers, req.Topic) == nil {
+			return fmt.Errorf("config: no handler for topic %q", req.Topic)
+		}
+	}
+	return nil
+}
+
+// handlerFor returns the handler of topic, or the one under "*".
+func handlerFor(handlers map[string]ContextHandler, topic string) ContextHandler {
+	if h := handlers[topic]; h != nil {
+		return h
+	}
+	return handlers["*"]
+}
+
+// Masked returns a copy of c with passwords and tokens replaced by "****".
+func (c *Config) Masked() Config {
+	m := *c
+	if m.Auth.Password != "" {
+		m.Auth.Password = maskedSecret
+	}
+	if m.Auth.Token != "" {
+		m.Auth.Token = maskedSecret
+	}
+	return m
+}
+
+// String returns c as YAML with secrets masked, e.g. for printing at startup.
+func (c *Config) String() string {
+	b, err := yaml.Marshal(c.Masked())
+	if err != nil {
+		return fmt.Sprintf("<config: %v>", err)
+	}
+	return string(b)
+}
+
+// LogValue implements slog.LogValuer so that logging a Config never prints its secrets.
+func (c *Config) LogValue() slog.Value {
+	return slog.StringValue(c.String())
+}
@@ -0,0 +1,215 @@
+package flowable
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// debug.go implements opt-in tracing of every HTTP exchange with Flowable,
+// with credentials and sensitive variable values redacted.
+
+// DefaultDebugBodyBytes is the default number of body bytes logged per request and response.
+const DefaultDebugBodyBytes = 4096
+
+// redacted replaces secret values in debug output.
+const redacted = "[REDACTED]"
+
+// DebugOptions configures HTTP debug tracing.
+type DebugOptions struct {
+	// MaxBodyBytes limits the logged size of each body (default DefaultDebugBodyBytes).
+	// A negative value omits bodies.
+	MaxBodyBytes int
+	// SensitiveVariables are variable names (case-insensitive) whose values are
+	// redacted, both in {"name": ..., "value": ...} variable objects and as
+	// object keys anywhere in a JSON body.
+	SensitiveVariables []string
+	// SensitiveHeaders are redacted in addition to Authorization,
+	// Proxy-Authorization, Cookie, Set-Cookie and the header of a HeaderAuthenticator.
+	SensitiveHeaders []string
+	// Logf receives the trace output as text. When nil, each exchange is
+	// logged as a structured record of component "http" at info level, so it
+	// follows SetEnableLogging and SetLogLevel(ComponentHTTP, ...).
+	Logf func(format string, args ...interface{})
+}
+
+var (
+	debugMu   sync.RWMutex
+	debugOpts *DebugOptions
+)
+
+// SetDebug enables tracing of every REST request made by the package: method,
+// URL, request headers, status, latency and both bodies. Pass nil to disable it.
+func SetDebug(opts *DebugOptions) {
+	debugMu.Lock()
+	defer debugMu.Unlock()
+	if opts == nil {
+		debugOpts = nil
+		return
+	}
+	o := *opts
+	if o.MaxBodyBytes == 0 {
+		o.MaxBodyBytes = DefaultDebugBodyBytes
+	}
+	debugOpts = &o
+}
+
+// currentDebug returns the options set by SetDebug, or nil when tracing is off.
+func currentDebug() *DebugOptions {
+	debugMu.RLock()
+	defer debugMu.RUnlock()
+	return debugOpts
+}
+
+// traceExchange logs one HTTP exchange.
+func traceExchange(opts *DebugOptions, req *http.Request, payload []byte, status int, body []byte, err error, elapsed time.Duration) {
+	if opts.Logf == nil {
+		if !logEnabled(ComponentHTTP, slog.LevelInfo) {
+			return
+		}
+		attrs := []slog.Attr{
+			slog.String("method", req.Method),
+			slog.String("url", req.URL.Redacted()),
+			slog.Int("status", status),
+			durationAttr(elapsed),
+			slog.String("requestHeaders", redactHeaders(opts, req.Header)),
+		}
+		if err != nil {
+			attrs = append(attrs, slog.Any("error", err))
+		}
+		if opts.MaxBodyBytes >= 0 {
+			if payload != nil {
+				attrs = append(attrs, slog.String("requestBody", redactBody(opts, payload)))
+			}
+			if body != nil {
+				attrs = append(attrs, slog.String("responseBody", redactBody(opts, body)))
+			}
+		}
+		logAt(req.Context(), ComponentHTTP, slog.LevelInfo, "http exchange", attrs...)
+		return
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "http: %s %s -> ", req.Method, req.URL.Redacted())
+	if err != nil && status == -1 {
+		fmt.Fprintf(&b, "error: %v", err)
+	} else {
+		fmt.Fprintf(&b, "%d", status)
+		if err != nil {
+			fmt.Fprintf(&b, " (%v)", err)
+		}
+	}
+	fmt.Fprintf(&b, " in %s", elapsed.Round(time.Millisecond))
+	fmt.Fprintf(&b, "\n  request headers: %s", redactHeaders(opts, req.Header))
+	if opts.MaxBodyBytes >= 0 {
+		if payload != nil {
+			fmt.Fprintf(&b, "\n  request body: %s", redactBody(opts, payload))
+		}
+		if body != nil {
+			fmt.Fprintf(&b, "\n  response body: %s", redactBody(opts, body))
+		}
+	}
+	opts.Logf("%s", b.String())
+}
+
+// redactHeaders formats h in sorted order with sensitive values replaced.
+func redactHeaders(opts *DebugOptions, h http.Header) string {
+	sensitive := map[string]bool{
+		"Authorization":       true,
+		"Proxy-Authorization": true,
+		"Cookie":              true,
+		"Set-Cookie":          true,
+	}
+	for _, name := range opts.SensitiveHeaders {
+		sensitive[http.CanonicalHeaderKey(name)] = true
+	}
+	switch a := currentAuthenticator().(type) {
+	case HeaderAuthenticator:
+		sensitive[http.CanonicalHeaderKey(a.Header)] = true
+	case *HeaderAuthenticator:
+		sensitive[http.CanonicalHeaderKey(a.Header)] = true
+	}
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		value := strings.Join(h[name], ", ")
+		if sensitive[http.CanonicalHeaderKey(name)] {
+			value = redacted
+		}
+		parts = append(parts, name+": "+value)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// redactBody returns body with sensitive variable values replaced, truncated
+// to opts.MaxBodyBytes. Bodies that are not JSON are only truncated.
+func redactBody(opts *DebugOptions, body []byte) string {
+	out := string(body)
+	if len(opts.SensitiveVariables) > 0 {
+		sensitive := make(map[string]bool, len(opts.SensitiveVariables))
+		for _, name := range opts.SensitiveVariables {
+			sensitive[strings.ToLower(name)] = true
+		}
+		var doc interface{}
+		if err := decodeNumbers(body, &doc); err == nil && redactValue(doc, sensitive) {
+			var buf bytes.Buffer
+			enc := json.NewEncoder(&buf)
+			enc.SetEscapeHTML(false)
+			if enc.Encode(doc) == nil {
+				out = strings.TrimSuffix(buf.String(), "\n")
+			}
+		}
+	}
+	return truncate(out, opts.MaxBodyBytes)
+}
+
+// redactValue replaces sensitive values inside v in place and reports whether it changed anything.
+func redactValue(v interface{}, sensitive map[string]bool) bool {
+	changed := false
+	switch n := v.(type) {
+	case map[string]interface{}:
+		if name, ok := n["name"].(string); ok && sensitive[strings.ToLower(name)] {
+			if _, ok := n["value"]; ok {
+				n["value"] = redacted
+				changed = true
+			}
+		}
+		for k, child := range n {
+			if sensitive[strings.ToLower(k)] {
+				n[k] = redacted
+				changed = true
+			} else if redactValue(child, sensitive) {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for _, child := range n {
+			if redactValue(child, sensitive) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// redactForLog prepares a response body for the regular log output, applying
+// the redaction settings of SetDebug when tracing is enabled.
+func redactForLog(body []byte) string {
+	opts := currentDebug()
+	if opts == nil {
+		opts = &DebugOptions{MaxBodyBytes: DefaultDebugBodyBytes}
+	}
+	if opts.MaxBodyBytes < 0 {
+		return "[omitted]"
+	}
+	return redactBody(opts, body)
+}
@@ -0,0 +1,451 @@
+package flowable
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// diagnose.go checks the connection from the worker to Flowable step by step
+// and explains how to fix what fails.
+
+// Names of the checks run by Diagnose, in order.
+const (
+	CheckURL      = "url"
+	CheckDNS      = "dns"
+	CheckTCP      = "tcp"
+	CheckTLS      = "tls"
+	CheckEndpoint = "endpoint"
+	CheckAuth     = "auth"
+	CheckClock    = "clock"
+	CheckAcquire  = "acquire"
+)
+
+// CheckStatus is the result of one check.
+type CheckStatus string
+
+const (
+	CheckPass CheckStatus = "pass"
+	CheckWarn CheckStatus = "warn"
+	CheckFail CheckStatus = "fail"
+	// CheckSkip means the check could not run because an earlier one failed,
+	// or does not apply, e.g. dns and tcp when requests go through a proxy.
+	CheckSkip CheckStatus = "skip"
+)
+
+// Thresholds of the clock and certificate checks.
+const (
+	ClockSkewWarn   = 5 * time.Second
+	ClockSkewFail   = time.Minute
+	CertExpiryWarn  = 14 * 24 * time.Hour
+	diagnoseTimeout = 10 * time.Second
+)
+
+// contextPaths are the usual context paths of Flowable applications, tried
+// when the external job API is not found below the base URL.
+var contextPaths = []string{"/flowable-work", "/flowable-rest", "/flowable-engage"}
+
+// DiagnosticCheck is the outcome of one check of Diagnose.
+type DiagnosticCheck struct {
+	Name   string      `json:"name"`
+	Status CheckStatus `json:"status"`
+	Detail string      `json:"detail"`
+	// Hint suggests how to fix a failed or suspicious check.
+	Hint string `json:"hint,omitempty"`
+}
+
+// Diagnosis is the report of Diagnose.
+type Diagnosis struct {
+	URL string `json:"url"`
+	// OK is false when a check failed; warnings do not count.
+	OK     bool              `json:"ok"`
+	Checks []DiagnosticCheck `json:"checks"`
+}
+
+// Diagnose checks that the external job API can be used at baseURL with the
+// current HTTP client, TLS settings and credentials:
+//
+//   - url: the base URL is well formed;
+//   - dns, tcp and tls: the host resolves, accepts connections and presents
+//     a certificate that is trusted and not about to expire;
+//   - endpoint: the external job API exists below the base URL, e.g. that
+//     the context path /flowable-work is not missing;
+//   - auth: the credentials are accepted (401) and allowed to use the API (403);
+//   - clock: the local clock agrees with the Date header of the server;
+//   - acquire: an acquire with numberOfTasks=0 succeeds, without locking any job.
+//
+// Checks that depend on a failed one are skipped. When the HTTP client uses a
+// proxy for baseURL (e.g. HTTPS_PROXY), dns, tcp and tls are skipped, since
+// the worker does not connect to the host itself, and the HTTP checks run
+// through the proxy. Diagnose bypasses the circuit breaker, so it can be used
+// while it is open.
+func Diagnose(ctx context.Context, baseURL string) *Diagnosis {
+	d := &Diagnosis{URL: baseURL, OK: true}
+	u, err := parseBaseURL(baseURL)
+	if err != nil {
+		d.add(CheckURL, CheckFail, err.Error(), `use the URL of the Flowable application, e.g. "http://localhost:8090/flowable-work"`)
+		return d.skipRest(CheckDNS, CheckTCP, CheckTLS, CheckEndpoint, CheckAuth, CheckClock, CheckAcquire)
+	}
+	d.add(CheckURL, CheckPass, baseURL, "")
+
+	if !d.checkConnection(ctx, u) {
+		return d.skipRest(CheckEndpoint, CheckAuth, CheckClock, CheckAcquire)
+	}
+	ok, clock := d.checkAPI(ctx, baseURL, u)
+	d.addCheck(clock)
+	if !ok {
+		return d.skipRest(CheckAcquire)
+	}
+	d.checkAcquire(ctx, baseURL)
+	return d
+}
+
+// checkConnection runs the dns, tcp and tls checks against the host of u, or
+// skips them when the HTTP client reaches it through a proxy. It reports
+// whether the HTTP checks can run.
+func (d *Diagnosis) checkConnection(ctx context.Context, u *url.URL) bool {
+	if proxy := proxyFor(u); proxy != nil {
+		detail := "requests go through the proxy " + proxy.Redacted()
+		d.add(CheckDNS, CheckSkip, detail, "")
+		d.add(CheckTCP, CheckSkip, detail, "")
+		d.add(CheckTLS, CheckSkip, detail, "")
+		return true
+	}
+	host, port := u.Hostname(), u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+	if !d.checkDNS(ctx, host) {
+		d.skipRest(CheckTCP, CheckTLS)
+		return false
+	}
+	if !d.checkTCP(ctx, net.JoinHostPort(host, port)) {
+		d.skipRest(CheckTLS)
+		return false
+	}
+	if u.Scheme != "https" {
+		d.add(CheckTLS, CheckSkip, "plain http", "")
+		return true
+	}
+	return d.checkTLS(ctx, host, net.JoinHostPort(host, port))
+}
+
+// proxyFor returns the proxy the HTTP client uses for u, or nil when it
+// connects directly. For a Transport that wraps another RoundTripper the
+// proxy is not visible, so the environment (HTTP_PROXY, HTTPS_PROXY,
+// NO_PROXY) is assumed, as http.DefaultTransport does.
+func proxyFor(u *url.URL) *url.URL {
+	proxyFunc := http.ProxyFromEnvironment
+	switch t := currentHTTPClient().Transport.(type) {
+	case *http.Transport:
+		proxyFunc = t.Proxy
+	case nil:
+		proxyFunc = http.DefaultTransport.(*http.Transport).Proxy
+	}
+	if proxyFunc == nil {
+		return nil
+	}
+	proxy, err := proxyFunc(&http.Request{Method: http.MethodGet, URL: u, Header: http.Header{}})
+	if err != nil {
+		return nil
+	}
+	return proxy
+}
+
+// add appends a check made of its fields.
+func (d *Diagnosis) add(name string, status CheckStatus, detail, hint string) {
+	d.addCheck(DiagnosticCheck{Name: name, Status: status, Detail: detail, Hint: hint})
+}
+
+// addCheck appends c; a failed check makes the diagnosis fail.
+func (d *Diagnosis) addCheck(c DiagnosticCheck) {
+	d.Checks = append(d.Checks, c)
+	if c.Status == CheckFail {
+		d.OK = false
+	}
+}
+
+// skipRest marks the checks that cannot run anymore and returns d.
+func (d *Diagnosis) skipRest(names ...string) *Diagnosis {
+	for _, name := range names {
+		d.add(name, CheckSkip, "skipped after a failed check", "")
+	}
+	return d
+}
+
+func (d *Diagnosis) checkDNS(ctx context.Context, host string) bool {
+	if net.ParseIP(host) != nil {
+		d.add(CheckDNS, CheckPass, host+" is an IP address", "")
+		return true
+	}
+	ctx, cancel := context.WithTimeout(ctx, diagnoseTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		d.add(CheckDNS, CheckFail, err.Error(), "check the host name for typos, and the DNS or /etc/hosts entry of "+host)
+		return false
+	}
+	d.add(CheckDNS, CheckPass, host+" resolves to "+strings.Join(addrs, ", "), "")
+	return true
+}
+
+func (d *Diagnosis) checkTCP(ctx context.Context, addr string) bool {
+	dialer := net.Dialer{Timeout: diagnoseTimeout}
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		d.add(CheckTCP, CheckFail, err.Error(), "check that Flowable is running and listening on "+addr+", and that no firewall blocks the port; when a proxy is required, set HTTP_PROXY or HTTPS_PROXY so that the worker uses it")
+		return false
+	}
+	conn.Close()
+	d.add(CheckTCP, CheckPass, fmt.Sprintf("connected to %s in %s", addr, time.Since(start).Round(time.Millisecond)), "")
+	return true
+}
+
+func (d *Diagnosis) checkTLS(ctx context.Context, host, addr string) bool {
+	cfg := &tls.Config{}
+	if t, ok := currentHTTPClient().Transport.(*http.Transport); ok && t.TLSClientConfig != nil {
+		cfg = t.TLSClientConfig.Clone()
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = host
+	}
+	dialer := tls.Dialer{NetDialer: &net.Dialer{Timeout: diagnoseTimeout}, Config: cfg}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		d.add(CheckTLS, CheckFail, err.Error(), tlsHint(err))
+		return false
+	}
+	defer conn.Close()
+	state := conn.(*tls.Conn).ConnectionState()
+	leaf := state.PeerCertificates[0]
+	detail := fmt.Sprintf("%s, certificate for %s valid until %s", tls.VersionName(state.Version), leaf.Subject.CommonName, leaf.NotAfter.Format(time.DateOnly))
+	if left := time.Until(leaf.NotAfter); left < CertExpiryWarn {
+		d.add(CheckTLS, CheckWarn, detail, fmt.Sprintf("the server certificate expires in %s; renew it", left.Round(time.Hour)))
+		return true
+	}
+	d.add(CheckTLS, CheckPass, detail, "")
+	return true
+}
+
+// tlsHint explains a failed TLS handshake.
+func tlsHint(err error) string {
+	var unknown x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	switch {
+	case errors.As(err, &unknown):
+		return "the server certificate is signed by an unknown CA; pass the CA bundle with TLSOptions.CAFile (tls.caFile in the configuration file)"
+	case errors.As(err, &hostname):
+		return "the certificate does not match the host name; use a host name listed in the certificate or set TLSOptions.ServerName"
+	case errors.As(err, &invalid) && invalid.Reason == x509.Expired:
+		return "the server certificate has expired or the local clock is wrong"
+	case strings.Contains(err.Error(), "first record does not look like a TLS handshake"):
+		return "the server speaks plain HTTP on this port; use http:// instead of https://"
+	}
+	return "check the TLS settings of the server and of TLSOptions"
+}
+
+// checkAPI runs the endpoint and auth checks with one request to the jobs
+// endpoint and returns the clock check made from its response. It returns
+// false when the API cannot be used.
+func (d *Diagnosis) checkAPI(ctx context.Context, baseURL string, u *url.URL) (bool, DiagnosticCheck) {
+	full, _ := endpointURL(baseURL, url.Values{"size": {"1"}}, "jobs")
+	start := time.Now()
+	status, header, body, err := probe(ctx, http.MethodGet, full, nil)
+	if err != nil {
+		d.add(CheckEndpoint, CheckFail, err.Error(), "the server accepts connections but does not answer HTTP requests; check the scheme and port")
+		d.add(CheckAuth, CheckSkip, "skipped after a failed check", "")
+		return false, DiagnosticCheck{Name: CheckClock, Status: CheckSkip, Detail: "skipped after a failed check"}
+	}
+	clock := clockCheck(header, start, time.Now())
+
+	switch {
+	case status == http.StatusNotFound:
+		d.add(CheckEndpoint, CheckFail, "404 Not Found at "+full, endpointHint(ctx, u))
+		d.add(CheckAuth, CheckSkip, "skipped after a failed check", "")
+		return false, clock
+	case status == http.StatusUnauthorized:
+		d.add(CheckEndpoint, CheckPass, "found at "+full, "")
+		d.add(CheckAuth, CheckFail, "401 Unauthorized: the credentials were rejected", authHint(http.StatusUnauthorized))
+		return false, clock
+	case status == http.StatusForbidden:
+		d.add(CheckEndpoint, CheckPass, "found at "+full, "")
+		d.add(CheckAuth, CheckFail, "403 Forbidden: the user is authenticated but not allowed to use the external job API", authHint(http.StatusForbidden))
+		return false, clock
+	case status >= 200 && status < 300 && !json.Valid(body):
+		d.add(CheckEndpoint, CheckFail, fmt.Sprintf("%d with a %s response instead of JSON at %s", status, header.Get("Content-Type"), full),
+			"another application or a login page answers at this URL; check the base URL and that no proxy redirects to a login")
+		d.add(CheckAuth, CheckSkip, "skipped after a failed check", "")
+		return false, clock
+	case status >= 200 && status < 300:
+		d.add(CheckEndpoint, CheckPass, "found at "+full, "")
+		d.add(CheckAuth, CheckPass, authDetail(), "")
+		return true, clock
+	}
+	d.add(CheckEndpoint, CheckFail, fmt.Sprintf("unexpected status %d at %s", status, full), "check the server logs; a 5xx status points to a problem in Flowable or in a proxy in front of it")
+	d.add(CheckAuth, CheckSkip, "skipped after a failed check", "")
+	return false, clock
+}
+
+// endpointHint explains a 404 from the jobs endpoint, looking for the API
+// at the usual context paths.
+func endpointHint(ctx context.Context, u *url.URL) string {
+	prefix := currentAPIPrefix()
+	path := strings.TrimRight(u.Path, "/")
+	if prefix != "" && strings.HasSuffix(path, prefix) {
+		return fmt.Sprintf("the base URL already ends with %s; remove it, the API prefix is added by the client", prefix)
+	}
+	root := u.Scheme + "://" + u.Host
+	candidates := []string{}
+	if path != "" {
+		candidates = append(candidates, root)
+	}
+	for _, p := range contextPaths {
+		if p != path {
+			candidates = append(candidates, root+p)
+		}
+	}
+	for _, candidate := range candidates {
+		full, err := endpointURL(candidate, nil, "jobs")
+		if err != nil {
+			continue
+		}
+		status, _, _, err := probe(ctx, http.MethodGet, full, nil)
+		if err == nil && (status < 300 || status == http.StatusUnauthorized || status == http.StatusForbidden) {
+			return fmt.Sprintf("the external job API answers at %s; use %s as the base URL", full, candidate)
+		}
+	}
+	return fmt.Sprintf("the base URL must include the context path of the Flowable application, e.g. %s/flowable-work; if the API is mounted elsewhere, set it with SetAPIPrefix (apiPrefix in the configuration file)", root)
+}
+
+// authHint explains a 401 or 403 response.
+func authHint(status int) string {
+	if status == http.StatusForbidden {
+		return "grant the user the privilege to use the external job API in Flowable, or use a user or token that has it"
+	}
+	if authDetail() == "no credentials configured" {
+		return "configure credentials with SetAuth, SetBearerToken or SetAuthenticator (auth in the configuration file)"
+	}
+	return "check the user name and password or the token; tokens may have expired"
+}
+
+// authDetail describes the configured credentials without revealing them.
+func authDetail() string {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	switch {
+	case authenticator != nil:
+		return fmt.Sprintf("accepted (%T)", authenticator)
+	case BearerToken != "":
+		return "accepted (bearer token)"
+	case AuthUser != "":
+		return "accepted (basic auth as " + AuthUser + ")"
+	}
+	return "no credentials configured"
+}
+
+// clockCheck compares the Date header with the local time of the request.
+func clockCheck(header http.Header, sent, received time.Time) DiagnosticCheck {
+	date, err := http.ParseTime(header.Get("Date"))
+	if err != nil {
+		return DiagnosticCheck{Name: CheckClock, Status: CheckSkip, Detail: "the server sent no Date header"}
+	}
+	// The Date header has a resolution of one second and is generated while
+	// the request is handled.
+	local := sent.Add(received.Sub(sent) / 2).Truncate(time.Second)
+	skew := date.Sub(local)
+	c := DiagnosticCheck{Name: CheckClock, Status: CheckPass, Detail: fmt.Sprintf("server clock differs by %s", skew)}
+	switch abs := max(skew, -skew); {
+	case abs >= ClockSkewFail:
+		c.Status = CheckFail
+	case abs >= ClockSkewWarn:
+		c.Status = CheckWarn
+	default:
+		return c
+	}
+	c.Hint = "synchronise the clocks of the worker and the server with NTP; lock expiry times are set by the server"
+	return c
+}
+
+// checkAcquire sends an acquire request that cannot lock any job.
+func (d *Diagnosis) checkAcquire(ctx context.Context, baseURL string) {
+	full, _ := endpointURL(baseURL, nil, "acquire", "jobs")
+	payload, _ := json.Marshal(AcquireRequest{Topic: "flowable-diagnose", LockDuration: "PT1M", NumberOfTasks: 0, WorkerId: "flowable-diagnose"})
+	status, _, body, err := probe(ctx, http.MethodPost, full, payload)
+	switch {
+	case err != nil:
+		d.add(CheckAcquire, CheckFail, err.Error(), "the jobs endpoint works but the acquire request failed; check proxies that block POST requests")
+	case status >= 200 && status < 300:
+		d.add(CheckAcquire, CheckPass, fmt.Sprintf("acquire with numberOfTasks=0 answered %d", status), "")
+	case status == http.StatusBadRequest:
+		d.add(CheckAcquire, CheckWarn, "the server rejects acquires with numberOfTasks=0: "+redactForLog(body),
+			"this Flowable version cannot do a dry acquire; the endpoint and credentials work")
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		d.add(CheckAcquire, CheckFail, fmt.Sprintf("%d %s", status, http.StatusText(status)), authHint(status))
+	default:
+		d.add(CheckAcquire, CheckFail, fmt.Sprintf("unexpected status %d: %s", status, redactForLog(body)), "check the server logs")
+	}
+}
+
+// probe sends one request with the configured client and credentials,
+// without the retries, limits and circuit breaker of the REST helpers.
+func probe(ctx context.Context, method, fullURL string, payload []byte) (status int, header http.Header, body []byte, err error) {
+	ctx, cancel := context.WithTimeout(ctx, diagnoseTimeout)
+	defer cancel()
+	var reqBody io.Reader
+	if payload != nil {
+		reqBody = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, reqBody)
+	if err != nil {
+		return -1, nil, nil, err
+	}
+	if err := prepareRequest(req); err != nil {
+		return -1, nil, nil, err
+	}
+	resp, err := currentHTTPClient().Do(req)
+	if err != nil {
+		return -1, nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err = io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode, resp.Header, body, err
+}
+
+// String formats the diagnosis as a table with one line per check, followed
+// by the hints.
+func (d *Diagnosis) String() string {
+	var b strings.Builder
+	tw := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	for _, c := range d.Checks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", strings.ToUpper(string(c.Status)), c.Name, c.Detail)
+		if c.Hint != "" {
+			fmt.Fprintf(tw, "\t\thint: %s\n", c.Hint)
+		}
+	}
+	tw.Flush()
+	failed := 0
+	for _, c := range d.Checks {
+		if c.Status == CheckFail {
+			failed++
+		}
+	}
+	if failed == 0 {
+		b.WriteString("all checks passed\n")
+	} else {
+		fmt.Fprintf(&b, "%d of %d checks failed\n", failed, len(d.Checks))
+	}
+	return b.String()
+}
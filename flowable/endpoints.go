@@ -0,0 +1,202 @@
+package flowable
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// endpoints.go spreads calls over several Flowable nodes: acquires rotate
+// over the healthy nodes and reports fail over to another reachable node.
+
+// NodeDownDuration is how long a node is skipped after a connection error or 5xx response.
+const NodeDownDuration = 30 * time.Second
+
+// endpointPool tracks the base URLs of one set of Flowable nodes and their health.
+type endpointPool struct {
+	urls   []string
+	key    string
+	refs   int  // guarded by poolsMu
+	pinned bool // guarded by poolsMu
+
+	mu        sync.Mutex
+	next      int
+	downUntil map[string]time.Time
+}
+
+// pools holds one endpointPool per distinct list of base URLs in use, so
+// health and round-robin state are shared by all subscriptions using the same
+// nodes. A pool is dropped when its last subscription releases it.
+var (
+	poolsMu sync.Mutex
+	pools   = map[string]*endpointPool{}
+)
+
+// holdPool returns the shared endpointPool for the base URLs of req. The
+// caller must call releasePool once it no longer uses it.
+func holdPool(req AcquireRequest) *endpointPool {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+	p := sharedPool(req)
+	p.refs++
+	return p
+}
+
+// pinPool returns the shared endpointPool for the base URLs of req and keeps
+// it for the life of the process. Acquire_jobs uses it, so that its calls
+// share node health and round-robin order.
+func pinPool(req AcquireRequest) *endpointPool {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+	p := sharedPool(req)
+	p.pinned = true
+	return p
+}
+
+// sharedPool returns the pool for the base URLs of req, creating it if
+// needed. poolsMu must be held.
+func sharedPool(req AcquireRequest) *endpointPool {
+	urls := baseURLs(req)
+	key := strings.Join(urls, "\n")
+	p := pools[key]
+	if p == nil {
+		p = &endpointPool{urls: urls, key: key, downUntil: make(map[string]time.Time)}
+		pools[key] = p
+	}
+	return p
+}
+
+// releasePool gives up a pool returned by holdPool.
+func releasePool(p *endpointPool) {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+	if p.refs--; p.refs <= 0 && !p.pinned && pools[p.key] == p {
+		delete(pools, p.key)
+	}
+}
+
+// baseURLs returns req.URL followed by req.URLs, without empty entries and duplicates.
+func baseURLs(req AcquireRequest) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, u := range append([]string{req.URL}, req.URLs...) {
+		u = strings.TrimRight(u, "/")
+		if u == "" || seen[u] {
+			continue
+		}
+		seen[u] = true
+		urls = append(urls, u)
+	}
+	if len(urls) == 0 {
+		urls = []string{req.URL}
+	}
+	return urls
+}
+
+// acquireOrder returns the nodes to try for an acquire: healthy nodes in
+// round-robin order, followed by the nodes currently marked down.
+func (p *endpointPool) acquireOrder() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	start := p.next % len(p.urls)
+	p.next++
+	rotated := append(append([]string(nil), p.urls[start:]...), p.urls[:start]...)
+	return p.healthyFirst(rotated)
+}
+
+// reportOrder returns the nodes to try for a report: preferred first when it
+// is healthy, then the other healthy nodes, then the nodes marked down.
+func (p *endpointPool) reportOrder(preferred string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ordered := []string{preferred}
+	for _, u := range p.urls {
+		if u != preferred {
+			ordered = append(ordered, u)
+		}
+	}
+	return p.healthyFirst(ordered)
+}
+
+// healthyFirst stably moves the nodes marked down to the end. p.mu must be held.
+func (p *endpointPool) healthyFirst(urls []string) []string {
+	now := time.Now()
+	var healthy, down []string
+	for _, u := range urls {
+		if now.Before(p.downUntil[u]) {
+			down = append(down, u)
+		} else {
+			healthy = append(healthy, u)
+		}
+	}
+	return append(healthy, down...)
+}
+
+// markDown skips u for NodeDownDuration.
+func (p *endpointPool) markDown(u string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.downUntil[u] = time.Now().Add(NodeDownDuration)
+}
+
+// markUp clears the down mark of u.
+func (p *endpointPool) markUp(u string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.downUntil, u)
+}
+
+// nodeFailed reports whether a call result indicates that the node itself is
+// unreachable, unhealthy or behind an open circuit, as opposed to rejecting
+// this particular request.
+func nodeFailed(ctx context.Context, status int, err error) bool {
+	if ctx.Err() != nil || isRequestError(err) {
+		return false
+	}
+	return status >= 500 || (err != nil && status == -1)
+}
+
+// reportWithFailover sends a report to the endpoint with the given path
+// segments on preferred, failing over to the other nodes when it is
+// unreachable. While the circuit of every node is open, it waits for one of
+// them to allow calls again (up to MaxReportCircuitWait in total) instead of
+// failing.
+func reportWithFailover(ctx context.Context, nodes *endpointPool, preferred string, segments []string, payload []byte) (status int, body []byte, err error) {
+	circuitCtx, cancel := context.WithTimeout(ctx, MaxReportCircuitWait)
+	defer cancel()
+	for {
+		status, body, err = reportToNodes(ctx, nodes, preferred, segments, payload)
+		if !errors.Is(err, ErrCircuitOpen) {
+			return status, body, err
+		}
+		if waitForCircuit(circuitCtx) != nil {
+			return status, body, err
+		}
+		metricReportRetries.add(1, "circuit-open")
+	}
+}
+
+// reportToNodes tries the nodes in report order until one of them answers.
+func reportToNodes(ctx context.Context, nodes *endpointPool, preferred string, segments []string, payload []byte) (status int, body []byte, err error) {
+	order := nodes.reportOrder(preferred)
+	for i, node := range order {
+		var full string
+		if full, err = endpointURL(node, nil, segments...); err != nil {
+			return -1, nil, err
+		}
+		status, body, err = reportWithRetry(ctx, full, payload)
+		if !nodeFailed(ctx, status, err) {
+			nodes.markUp(node)
+			return status, body, err
+		}
+		nodes.markDown(node)
+		if i < len(order)-1 {
+			metricReportRetries.add(1, "failover")
+			logAt(ctx, ComponentReport, slog.LevelWarn, "node failed, trying next node", slog.String("node", node), slog.Int("status", status), slog.Any("error", err))
+		}
+	}
+	return status, body, err
+}
@@ -0,0 +1,292 @@
+package flowable
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonpath.go implements a small JSONPath-like syntax for reading and writing
+// values nested inside complex (typically "json") variables.
+//
+// A path starts with the variable name, optionally prefixed by "$.", followed
+// by any number of field accessors (".field" or "['field']"), array indexes
+// ("[0]") and wildcards (".*" or "[*]"), e.g. "order.items[0].sku" or
+// "order.items[*].sku".
+
+// ErrPathNotFound is returned by Lookup when the path does not resolve to a value.
+var ErrPathNotFound = errors.New("path not found")
+
+type pathSegmentKind int
+
+const (
+	segmentField pathSegmentKind = iota
+	segmentIndex
+	segmentWildcard
+)
+
+// maxSetIndex is the largest array index SetPath accepts. Arrays are padded
+// with nulls up to the index, so the limit bounds the memory a path can claim.
+const maxSetIndex = 1000
+
+type pathSegment struct {
+	kind  pathSegmentKind
+	name  string
+	index int
+}
+
+// Lookup resolves path against the given variables and returns the value it
+// points to with its decoded Go type (string, float64, int64, json.Number,
+// bool, map[string]interface{} or []interface{}). When the path contains a
+// wildcard, all matches are returned as a []interface{}. Lookup returns
+// ErrPathNotFound (wrapped) when any part of the path is missing.
+func Lookup(vars []HandlerVariable, path string) (interface{}, error) {
+	name, segments, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range vars {
+		if v.Name != name {
+			continue
+		}
+		root := variableTree(v)
+		matches, wildcard, err := resolvePath(root, segments)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if wildcard {
+			return matches, nil
+		}
+		return matches[0], nil
+	}
+	return nil, fmt.Errorf("%s: %w: no variable %q", path, ErrPathNotFound, name)
+}
+
+// SetPath sets value at path, creating the variable and any intermediate
+// objects or arrays that do not exist yet, and returns the updated slice.
+// A newly created variable gets type "json" when the path is nested and a
+// type inferred from value otherwise; an existing variable set through a
+// nested path becomes "json". Nested maps and slices already held by
+// vars are modified in place. Wildcards are not allowed, and an index that
+// grows an array may not exceed 1000.
+func SetPath(vars []HandlerVariable, path string, value interface{}) ([]HandlerVariable, error) {
+	name, segments, err := parsePath(path)
+	if err != nil {
+		return vars, err
+	}
+	for _, seg := range segments {
+		if seg.kind == segmentWildcard {
+			return vars, fmt.Errorf("%s: wildcards cannot be used with SetPath", path)
+		}
+	}
+	idx := -1
+	for i, v := range vars {
+		if v.Name == name {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		varType := "json"
+		if len(segments) == 0 {
+			varType = inferVariableType(value)
+		}
+		vars = append(vars, HandlerVariable{Name: name, Type: varType})
+		idx = len(vars) - 1
+	}
+	if len(segments) == 0 {
+		vars[idx].Value = value
+		return vars, nil
+	}
+	updated, err := setIn(variableTree(vars[idx]), segments, value)
+	if err != nil {
+		return vars, fmt.Errorf("%s: %w", path, err)
+	}
+	// The value is now an object or array, which Flowable only accepts as json.
+	vars[idx].Value = updated
+	vars[idx].Type = "json"
+	return vars, nil
+}
+
+// variableTree returns the value of v, decoding it first when a "json"
+// variable carries its document as a string.
+func variableTree(v HandlerVariable) interface{} {
+	if s, ok := v.Value.(string); ok && v.Type == "json" {
+		var decoded interface{}
+		if err := decodeNumbers([]byte(s), &decoded); err == nil {
+			return decodeVariableValue("", decoded)
+		}
+	}
+	return v.Value
+}
+
+// resolvePath walks segments starting at root. The boolean result reports
+// whether a wildcard was involved, in which case all matches are returned.
+func resolvePath(root interface{}, segments []pathSegment) ([]interface{}, bool, error) {
+	current := []interface{}{root}
+	wildcard := false
+	for _, seg := range segments {
+		var next []interface{}
+		for _, node := range current {
+			switch seg.kind {
+			case segmentField:
+				m, ok := node.(map[string]interface{})
+				if !ok {
+					if wildcard {
+						continue
+					}
+					return nil, false, fmt.Errorf("%w: %q is not an object", ErrPathNotFound, seg.name)
+				}
+				child, ok := m[seg.name]
+				if !ok {
+					if wildcard {
+						continue
+					}
+					return nil, false, fmt.Errorf("%w: no field %q", ErrPathNotFound, seg.name)
+				}
+				next = append(next, child)
+			case segmentIndex:
+				arr, ok := node.([]interface{})
+				if !ok || seg.index >= len(arr) {
+					if wildcard {
+						continue
+					}
+					return nil, false, fmt.Errorf("%w: no index %d", ErrPathNotFound, seg.index)
+				}
+				next = append(next, arr[seg.index])
+			case segmentWildcard:
+				switch n := node.(type) {
+				case []interface{}:
+					next = append(next, n...)
+				case map[string]interface{}:
+					for _, k := range sortedKeys(n) {
+						next = append(next, n[k])
+					}
+				}
+			}
+		}
+		if seg.kind == segmentWildcard {
+			wildcard = true
+		}
+		current = next
+	}
+	if current == nil {
+		current = []interface{}{}
+	}
+	return current, wildcard, nil
+}
+
+// setIn stores value at segments below node and returns the (possibly newly
+// created or grown) node.
+func setIn(node interface{}, segments []pathSegment, value interface{}) (interface{}, error) {
+	if len(segments) == 0 {
+		return value, nil
+	}
+	seg := segments[0]
+	switch seg.kind {
+	case segmentField:
+		m, ok := node.(map[string]interface{})
+		if node == nil {
+			m, ok = map[string]interface{}{}, true
+		}
+		if !ok {
+			return nil, fmt.Errorf("cannot set field %q on %T", seg.name, node)
+		}
+		child, err := setIn(m[seg.name], segments[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		m[seg.name] = child
+		return m, nil
+	case segmentIndex:
+		arr, ok := node.([]interface{})
+		if node == nil {
+			ok = true
+		}
+		if !ok {
+			return nil, fmt.Errorf("cannot set index %d on %T", seg.index, node)
+		}
+		if seg.index >= len(arr) && seg.index > maxSetIndex {
+			return nil, fmt.Errorf("index %d exceeds the limit of %d for new array elements", seg.index, maxSetIndex)
+		}
+		for len(arr) <= seg.index {
+			arr = append(arr, nil)
+		}
+		child, err := setIn(arr[seg.index], segments[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		arr[seg.index] = child
+		return arr, nil
+	}
+	return nil, fmt.Errorf("unsupported path segment")
+}
+
+// parsePath splits path into the variable name and the segments below it.
+func parsePath(path string) (string, []pathSegment, error) {
+	p := strings.TrimPrefix(path, "$.")
+	end := strings.IndexAny(p, ".[")
+	if end == -1 {
+		end = len(p)
+	}
+	name := p[:end]
+	if name == "" || name == "*" {
+		return "", nil, fmt.Errorf("invalid path %q: missing variable name", path)
+	}
+	var segments []pathSegment
+	rest := p[end:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			n := strings.IndexAny(rest, ".[")
+			if n == -1 {
+				n = len(rest)
+			}
+			field := rest[:n]
+			if field == "" {
+				return "", nil, fmt.Errorf("invalid path %q: empty field name", path)
+			}
+			if field == "*" {
+				segments = append(segments, pathSegment{kind: segmentWildcard})
+			} else {
+				segments = append(segments, pathSegment{kind: segmentField, name: field})
+			}
+			rest = rest[n:]
+		case '[':
+			closing := strings.IndexByte(rest, ']')
+			if closing == -1 {
+				return "", nil, fmt.Errorf("invalid path %q: unclosed '['", path)
+			}
+			inner := rest[1:closing]
+			switch {
+			case inner == "*":
+				segments = append(segments, pathSegment{kind: segmentWildcard})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				segments = append(segments, pathSegment{kind: segmentField, name: inner[1 : len(inner)-1]})
+			default:
+				i, err := strconv.Atoi(inner)
+				if err != nil || i < 0 {
+					return "", nil, fmt.Errorf("invalid path %q: bad index %q", path, inner)
+				}
+				segments = append(segments, pathSegment{kind: segmentIndex, index: i})
+			}
+			rest = rest[closing+1:]
+		default:
+			return "", nil, fmt.Errorf("invalid path %q", path)
+		}
+	}
+	return name, segments, nil
+}
+
+// sortedKeys returns the keys of m in ascending order.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,157 @@
+package flowable
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// logging.go routes the package's log output through log/slog. Every record
+// carries a "component" attribute; records about a job also carry the job's
+// topic, workerId, jobId and processInstanceId.
+
+// Components used for the "component" attribute and SetLogLevel.
+const (
+	ComponentSubscribe = "subscribe"
+	ComponentAcquire   = "acquire"
+	ComponentHandler   = "handler"
+	ComponentReport    = "report"
+	ComponentHTTP      = "http"
+	ComponentCircuit   = "circuit"
+	ComponentTLS       = "tls"
+	ComponentAuth      = "auth"
+)
+
+// Attribute keys used in log records.
+const (
+	AttrComponent         = "component"
+	AttrTopic             = "topic"
+	AttrWorkerId          = "workerId"
+	AttrJobId             = "jobId"
+	AttrProcessInstanceId = "processInstanceId"
+	AttrOutcome           = "outcome"
+	AttrDuration          = "duration"
+)
+
+var (
+	loggerMu        sync.RWMutex
+	logger          *slog.Logger
+	componentLevels = map[string]slog.Level{}
+	loggingDisabled atomic.Bool
+)
+
+// EnableLogging is still honoured: while it is false, no log output is
+// written, whatever SetEnableLogging was called with.
+//
+// Deprecated: use SetEnableLogging, which is safe to call while
+// subscriptions run; assigning EnableLogging is not.
+var EnableLogging = true
+
+// SetEnableLogging turns all log output of the package on or off (default on).
+// It may be called while subscriptions run. Use SetLogger and SetLogLevel for
+// finer control.
+func SetEnableLogging(enabled bool) {
+	loggingDisabled.Store(!enabled)
+}
+
+// SetLogger sets the logger used by the package. Pass nil to use slog.Default().
+func SetLogger(l *slog.Logger) {
+	loggerMu.Lock()
+	defer loggerMu.Unlock()
+	logger = l
+}
+
+// Logger returns the logger set by SetLogger, or slog.Default().
+func Logger() *slog.Logger {
+	loggerMu.RLock()
+	defer loggerMu.RUnlock()
+	if logger == nil {
+		return slog.Default()
+	}
+	return logger
+}
+
+// NewJSONLogger returns a logger writing JSON records at level and above to w,
+// as typically wanted in production.
+func NewJSONLogger(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+// SetLogLevel sets the minimum level of the records logged by component
+// (default slog.LevelInfo). Records must also pass the level of the handler
+// of the logger, so to see debug records of one component, give the handler
+// slog.LevelDebug.
+func SetLogLevel(component string, level slog.Level) {
+	loggerMu.Lock()
+	defer loggerMu.Unlock()
+	componentLevels[component] = level
+}
+
+// logEnabled reports whether records of component at level are logged.
+func logEnabled(component string, level slog.Level) bool {
+	if loggingDisabled.Load() || !EnableLogging {
+		return false
+	}
+	loggerMu.RLock()
+	min, ok := componentLevels[component]
+	loggerMu.RUnlock()
+	if !ok {
+		min = slog.LevelInfo
+	}
+	return level >= min
+}
+
+// logAt logs msg with the logger of ctx (see LoggerFromContext) when
+// component is enabled at level.
+func logAt(ctx context.Context, component string, level slog.Level, msg string, attrs ...slog.Attr) {
+	if !logEnabled(component, level) {
+		return
+	}
+	attrs = append([]slog.Attr{slog.String(AttrComponent, component)}, attrs...)
+	LoggerFromContext(ctx).LogAttrs(ctx, level, msg, attrs...)
+}
+
+type loggerKey struct{}
+
+// ContextWithLogger returns a copy of ctx carrying l.
+func ContextWithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// LoggerFromContext returns the logger carried by ctx, or Logger(). Inside a
+// handler called by Subscribe this is a job-scoped logger carrying the topic,
+// workerId, jobId and processInstanceId of the job.
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok && l != nil {
+			return l
+		}
+	}
+	return Logger()
+}
+
+type topicKey struct{}
+
+// jobContext returns ctx carrying the topic and a logger scoped to one acquired job.
+func jobContext(ctx context.Context, req AcquireRequest, jobId, processInstanceId string) context.Context {
+	ctx = context.WithValue(ctx, topicKey{}, req.Topic)
+	attrs := []any{slog.String(AttrTopic, req.Topic), slog.String(AttrWorkerId, req.WorkerId), slog.String(AttrJobId, jobId)}
+	if processInstanceId != "" {
+		attrs = append(attrs, slog.String(AttrProcessInstanceId, processInstanceId))
+	}
+	return ContextWithLogger(ctx, LoggerFromContext(ctx).With(attrs...))
+}
+
+// topicFromContext returns the topic of the job handled in ctx, or "".
+func topicFromContext(ctx context.Context) string {
+	topic, _ := ctx.Value(topicKey{}).(string)
+	return topic
+}
+
+// durationAttr formats d for log records.
+func durationAttr(d time.Duration) slog.Attr {
+	return slog.Duration(AttrDuration, d)
+}
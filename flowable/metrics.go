@@ -0,0 +1,201 @@
+package flowable
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metrics.go keeps worker metrics and exposes them in the Prometheus text
+// exposition format, without depending on the Prometheus client library.
+
+// MetricsContentType is the content type of the metrics exposition.
+const MetricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultDurationBuckets are the histogram buckets, in seconds, used for durations.
+var DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300}
+
+// metricKind is the Prometheus type of a metric family.
+type metricKind string
+
+const (
+	kindCounter   metricKind = "counter"
+	kindGauge     metricKind = "gauge"
+	kindHistogram metricKind = "histogram"
+)
+
+// metricFamily is one named metric with a fixed set of label names.
+type metricFamily struct {
+	name    string
+	help    string
+	kind    metricKind
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*metricSeries
+}
+
+// metricSeries is the state of one label combination.
+type metricSeries struct {
+	values []string
+	value  float64
+	counts []uint64 // histogram buckets, not cumulative
+	sum    float64
+	count  uint64
+}
+
+func newFamily(name, help string, kind metricKind, labels ...string) *metricFamily {
+	f := &metricFamily{name: name, help: help, kind: kind, labels: labels, series: map[string]*metricSeries{}}
+	if kind == kindHistogram {
+		f.buckets = DefaultDurationBuckets
+	}
+	return f
+}
+
+// get returns the series for values, creating it if needed. f.mu must be held.
+func (f *metricFamily) get(values []string) *metricSeries {
+	key := strings.Join(values, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &metricSeries{values: append([]string(nil), values...)}
+		if f.kind == kindHistogram {
+			s.counts = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// add adds delta to a counter or gauge.
+func (f *metricFamily) add(delta float64, values ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.get(values).value += delta
+}
+
+// observe records v in a histogram.
+func (f *metricFamily) observe(v float64, values ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := f.get(values)
+	for i, upper := range f.buckets {
+		if v <= upper {
+			s.counts[i]++
+			break
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+// write writes f in the text exposition format.
+func (f *metricFamily) write(w io.Writer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+	keys := make([]string, 0, len(f.series))
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := f.series[k]
+		if f.kind != kindHistogram {
+			fmt.Fprintf(w, "%s%s %s\n", f.name, formatLabels(f.labels, s.values, "", ""), formatFloat(s.value))
+			continue
+		}
+		var cumulative uint64
+		for i, upper := range f.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, formatLabels(f.labels, s.values, "le", formatFloat(upper)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, formatLabels(f.labels, s.values, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", f.name, formatLabels(f.labels, s.values, "", ""), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", f.name, formatLabels(f.labels, s.values, "", ""), s.count)
+	}
+}
+
+// formatLabels renders a label set, with an optional extra label such as "le".
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	var parts []string
+	for i, name := range names {
+		parts = append(parts, name+`="`+escapeLabel(values[i])+`"`)
+	}
+	if extraName != "" {
+		parts = append(parts, extraName+`="`+extraValue+`"`)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// escapeLabel escapes a label value as required by the text format.
+func escapeLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+// formatFloat renders v as a Prometheus sample value.
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Worker metrics.
+var (
+	metricPolls           = newFamily("flowable_acquire_polls_total", "Acquire requests made.", kindCounter, "topic")
+	metricEmptyPolls      = newFamily("flowable_acquire_empty_polls_total", "Acquire requests that returned no jobs.", kindCounter, "topic")
+	metricAcquireErrors   = newFamily("flowable_acquire_errors_total", "Acquire requests that failed.", kindCounter, "topic")
+	metricAcquireDuration = newFamily("flowable_acquire_duration_seconds", "Latency of acquire requests.", kindHistogram, "topic")
+	metricAcquired        = newFamily("flowable_jobs_acquired_total", "Jobs acquired.", kindCounter, "topic")
+	metricReported        = newFamily("flowable_jobs_reported_total", "Jobs reported, by outcome (complete, fail, bpmnError, cmmnTerminate).", kindCounter, "topic", "outcome")
+	metricReportErrors    = newFamily("flowable_report_errors_total", "Reports that failed or were rejected.", kindCounter, "topic", "outcome")
+	metricReportRetries   = newFamily("flowable_report_retries_total", "Report attempts repeated, by reason (back-pressure, circuit-open, failover).", kindCounter, "reason")
+	metricLockLost        = newFamily("flowable_lock_lost_total", "Reports rejected because the job lock was lost (404 or 409).", kindCounter, "topic")
+	metricHandlerDuration = newFamily("flowable_handler_duration_seconds", "Time spent in the job handler.", kindHistogram, "topic")
+	metricInFlight        = newFamily("flowable_jobs_in_flight", "Jobs acquired and not reported yet.", kindGauge, "topic")
+
+	metricFamilies = []*metricFamily{
+		metricPolls, metricEmptyPolls, metricAcquireErrors, metricAcquireDuration, metricAcquired,
+		metricReported, metricReportErrors, metricReportRetries, metricLockLost, metricHandlerDuration, metricInFlight,
+	}
+)
+
+// WriteMetrics writes all worker metrics to w in the Prometheus text format.
+func WriteMetrics(w io.Writer) {
+	for _, f := range metricFamilies {
+		f.write(w)
+	}
+}
+
+// MetricsHandler returns an http.Handler serving the worker metrics.
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", MetricsContentType)
+		WriteMetrics(w)
+	})
+}
+
+// ServeMetrics starts an HTTP listener on addr serving the metrics at /metrics.
+// It returns once the listener is open; stop it with Shutdown or Close.
+func ServeMetrics(addr string) (*http.Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", MetricsHandler())
+	srv := &http.Server{Addr: ln.Addr().String(), Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go srv.Serve(ln)
+	return srv, nil
+}
@@ -0,0 +1,170 @@
+package flowable
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// oauth2.go implements the OAuth2 client-credentials grant as an Authenticator.
+
+// DefaultTokenRefreshBefore is how long before expiry a cached token is refreshed
+// when OAuth2ClientCredentials.RefreshBefore is zero.
+const DefaultTokenRefreshBefore = time.Minute
+
+// OAuth2ClientCredentials is an Authenticator that obtains bearer tokens from
+// TokenURL with the client-credentials grant. Tokens are cached and refreshed
+// RefreshBefore ahead of their expiry; a 401 from Flowable discards the cached
+// token so that the request is retried with a new one.
+type OAuth2ClientCredentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// EndpointParams are additional form parameters sent to the token endpoint (e.g. "audience").
+	EndpointParams url.Values
+	// CredentialsInBody sends client_id/client_secret as form parameters instead of basic auth.
+	CredentialsInBody bool
+	// RefreshBefore defaults to DefaultTokenRefreshBefore.
+	RefreshBefore time.Duration
+	// HTTPClient is used for token requests; defaults to a client with a 30 second timeout.
+	HTTPClient *http.Client
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// NewOAuth2ClientCredentials returns a client-credentials Authenticator for the given token endpoint.
+func NewOAuth2ClientCredentials(tokenURL, clientID, clientSecret string, scopes ...string) *OAuth2ClientCredentials {
+	return &OAuth2ClientCredentials{
+		TokenURL:     tokenURL,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       scopes,
+	}
+}
+
+// Authenticate sets the Authorization header to a valid bearer token.
+func (o *OAuth2ClientCredentials) Authenticate(req *http.Request) error {
+	token, err := o.Token()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// Invalidate discards the cached token so the next request fetches a new one.
+func (o *OAuth2ClientCredentials) Invalidate() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.token = ""
+	o.expiry = time.Time{}
+}
+
+// Token returns the cached access token, fetching a new one when there is
+// none or when it expires within RefreshBefore.
+func (o *OAuth2ClientCredentials) Token() (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	refreshBefore := o.RefreshBefore
+	if refreshBefore == 0 {
+		refreshBefore = DefaultTokenRefreshBefore
+	}
+	if o.token != "" && (o.expiry.IsZero() || time.Now().Add(refreshBefore).Before(o.expiry)) {
+		return o.token, nil
+	}
+	token, expiresIn, err := o.fetchToken()
+	if err != nil {
+		return "", err
+	}
+	o.token = token
+	o.expiry = time.Time{}
+	if expiresIn > 0 {
+		o.expiry = time.Now().Add(expiresIn)
+	}
+	return o.token, nil
+}
+
+// tokenResponse is the token endpoint response defined by RFC 6749 section 5.1.
+type tokenResponse struct {
+	AccessToken      string      `json:"access_token"`
+	TokenType        string      `json:"token_type"`
+	ExpiresIn        json.Number `json:"expires_in"`
+	Error            string      `json:"error"`
+	ErrorDescription string      `json:"error_description"`
+}
+
+// fetchToken performs the client-credentials request against TokenURL.
+func (o *OAuth2ClientCredentials) fetchToken() (string, time.Duration, error) {
+	form := url.Values{}
+	for k, v := range o.EndpointParams {
+		form[k] = v
+	}
+	form.Set("grant_type", "client_credentials")
+	if len(o.Scopes) > 0 {
+		form.Set("scope", strings.Join(o.Scopes, " "))
+	}
+	if o.CredentialsInBody {
+		form.Set("client_id", o.ClientID)
+		form.Set("client_secret", o.ClientSecret)
+	}
+	req, err := http.NewRequest("POST", o.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if !o.CredentialsInBody {
+		req.SetBasicAuth(url.QueryEscape(o.ClientID), url.QueryEscape(o.ClientSecret))
+	}
+
+	client := o.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("oauth2: token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", 0, fmt.Errorf("oauth2: reading token response: %w", err)
+	}
+
+	var tr tokenResponse
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return "", 0, fmt.Errorf("oauth2: token endpoint returned status %d: %s", resp.StatusCode, truncate(string(body), 200))
+	}
+	if resp.StatusCode != http.StatusOK || tr.AccessToken == "" {
+		if tr.Error != "" {
+			return "", 0, fmt.Errorf("oauth2: token endpoint returned status %d: %s %s", resp.StatusCode, tr.Error, tr.ErrorDescription)
+		}
+		return "", 0, fmt.Errorf("oauth2: token endpoint returned status %d without an access token", resp.StatusCode)
+	}
+	var expiresIn time.Duration
+	if secs, err := tr.ExpiresIn.Int64(); err == nil && secs > 0 {
+		expiresIn = time.Duration(secs) * time.Second
+	}
+	return tr.AccessToken, expiresIn, nil
+}
+
+// truncate shortens s to at most n bytes, marking the cut with "...". The
+// cut is moved back to a rune boundary, so that the result stays valid UTF-8.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "..."
+}
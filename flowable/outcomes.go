@@ -0,0 +1,208 @@
+package flowable
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// outcomes.go maps errors returned by handlers to Flowable job outcomes, so
+// handlers can signal bpmnError, cmmnTerminate or a retry policy by returning
+// an error instead of building a HandlerStatus/HandlerResult pair by hand.
+
+// BPMNErrorOutcome is the error returned by BPMNError. It makes the job end
+// with a BPMN error carrying ErrorCode and Variables.
+type BPMNErrorOutcome struct {
+	ErrorCode string
+	Variables []HandlerVariable
+}
+
+func (e *BPMNErrorOutcome) Error() string {
+	return "bpmn error " + e.ErrorCode
+}
+
+// BPMNError returns an error that throws a BPMN error with the given code and variables.
+func BPMNError(errorCode string, vars []HandlerVariable) error {
+	return &BPMNErrorOutcome{ErrorCode: errorCode, Variables: vars}
+}
+
+// TerminateOutcome is the error returned by Terminate. It makes the job end
+// with a CMMN terminate carrying Variables.
+type TerminateOutcome struct {
+	Variables []HandlerVariable
+}
+
+func (e *TerminateOutcome) Error() string {
+	return "cmmn terminate"
+}
+
+// Terminate returns an error that terminates the CMMN plan item, optionally with variables.
+func Terminate(vars ...HandlerVariable) error {
+	return &TerminateOutcome{Variables: vars}
+}
+
+// RetryableError is the error returned by Retryable. It fails the job and asks
+// Flowable to retry it after After.
+type RetryableError struct {
+	Err   error
+	After time.Duration
+}
+
+func (e *RetryableError) Error() string {
+	if e.Err == nil {
+		return "retryable error"
+	}
+	return e.Err.Error()
+}
+
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable returns an error that fails the job with a retry timeout of after.
+// A zero after leaves the retry timeout to the server.
+func Retryable(err error, after time.Duration) error {
+	return &RetryableError{Err: err, After: after}
+}
+
+// PermanentError is the error returned by Permanent. It fails the job without
+// any retries left.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	if e.Err == nil {
+		return "permanent error"
+	}
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent returns an error that fails the job with retries set to 0.
+func Permanent(err error) error {
+	return &PermanentError{Err: err}
+}
+
+// ErrorRule maps an error to another error, typically one of the outcome
+// errors above. It returns nil when it does not apply.
+type ErrorRule func(err error) error
+
+// MatchError returns an ErrorRule that applies fn to errors matching T through errors.As.
+func MatchError[T error](fn func(T) error) ErrorRule {
+	return func(err error) error {
+		var target T
+		if errors.As(err, &target) {
+			return fn(target)
+		}
+		return nil
+	}
+}
+
+// ErrorPolicy decides the job outcome for errors returned by handlers. Its
+// rules are tried in order and the first non-nil result is used; the outcome
+// errors (BPMNError, Terminate, Retryable, Permanent) are always recognized,
+// anywhere in the error chain. Any other error fails the job with its message.
+type ErrorPolicy struct {
+	rules []ErrorRule
+}
+
+// NewErrorPolicy returns a policy that applies the given rules before the built-in outcomes.
+func NewErrorPolicy(rules ...ErrorRule) *ErrorPolicy {
+	return &ErrorPolicy{rules: rules}
+}
+
+// Outcome returns the handler status and result for err. A nil err is a success.
+func (p *ErrorPolicy) Outcome(err error) (HandlerStatus, *HandlerResult) {
+	if err == nil {
+		return HandlerSuccess, &HandlerResult{Status: HandlerSuccess, Variables: []HandlerVariable{}}
+	}
+	if p != nil {
+		for _, rule := range p.rules {
+			if mapped := rule(err); mapped != nil {
+				err = mapped
+				break
+			}
+		}
+	}
+
+	var bpmnErr *BPMNErrorOutcome
+	var terminate *TerminateOutcome
+	var retryable *RetryableError
+	var permanent *PermanentError
+	switch {
+	case errors.As(err, &bpmnErr):
+		return HandlerBPMNError, &HandlerResult{Status: HandlerBPMNError, ErrorCode: bpmnErr.ErrorCode, Variables: nonNilVariables(bpmnErr.Variables)}
+	case errors.As(err, &terminate):
+		return HandlerCMMNTerminate, &HandlerResult{Status: HandlerCMMNTerminate, Variables: nonNilVariables(terminate.Variables)}
+	case errors.As(err, &permanent):
+		retries := 0
+		status, res := failResult(err.Error(), "")
+		res.Retries = &retries
+		return status, res
+	case errors.As(err, &retryable):
+		status, res := failResult(err.Error(), "")
+		res.RetryTimeout = isoDuration(retryable.After)
+		return status, res
+	default:
+		return failResult(err.Error(), "")
+	}
+}
+
+var (
+	errorPolicyMu sync.RWMutex
+	errorPolicy   = NewErrorPolicy()
+)
+
+// SetErrorPolicy sets the policy used by ErrorOutcome, TypedHandler and ErrorHandler.
+func SetErrorPolicy(p *ErrorPolicy) {
+	errorPolicyMu.Lock()
+	defer errorPolicyMu.Unlock()
+	errorPolicy = p
+}
+
+// ErrorOutcome maps err to a handler status and result using the policy set by SetErrorPolicy.
+func ErrorOutcome(err error) (HandlerStatus, *HandlerResult) {
+	errorPolicyMu.RLock()
+	p := errorPolicy
+	errorPolicyMu.RUnlock()
+	return p.Outcome(err)
+}
+
+// ErrorHandler adapts a function that returns its output variables and an
+// error to a ContextHandler. A nil error completes the job with the returned
+// variables; any other error is routed through ErrorOutcome.
+func ErrorHandler(fn func(ctx context.Context, status int, body string) ([]HandlerVariable, error)) ContextHandler {
+	return func(ctx context.Context, status int, body string) (HandlerStatus, *HandlerResult) {
+		vars, err := fn(ctx, status, body)
+		resStatus, res := ErrorOutcome(err)
+		if err == nil {
+			res.Variables = nonNilVariables(vars)
+		}
+		return resStatus, res
+	}
+}
+
+// nonNilVariables returns vars, or an empty slice when vars is nil, so that
+// results always marshal "variables" as an array.
+func nonNilVariables(vars []HandlerVariable) []HandlerVariable {
+	if vars == nil {
+		return []HandlerVariable{}
+	}
+	return vars
+}
+
+// isoDuration formats d as an ISO-8601 duration in whole seconds (rounded up),
+// or returns "" for non-positive durations.
+func isoDuration(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("PT%dS", int64(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,181 @@
+package flowable
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// reload.go keeps the subscriptions of a Worker in line with its
+// configuration file while the worker is running.
+
+// DefaultWatchInterval is how often Watch checks the configuration file when
+// no interval is given.
+const DefaultWatchInterval = 5 * time.Second
+
+// Reloader runs the subscriptions described by a configuration file and
+// applies changes to the file without a restart:
+//
+//   - topics added to the file are subscribed and removed ones stop
+//     acquiring, after finishing their in-flight jobs;
+//   - changed topic settings (concurrency, interval, lock duration...) are
+//     used from the next acquire on;
+//   - changed credentials, TLS settings and API prefix are used for the next
+//     requests, including the reports of jobs in flight.
+//
+// A configuration that does not load, validate or apply is rejected and the
+// running one is kept.
+type Reloader struct {
+	path     string
+	worker   *Worker
+	handlers map[string]ContextHandler
+	ctx      context.Context
+
+	mu      sync.Mutex
+	current *Config
+	subs    map[string]*Subscription
+	stamp   fileStamp
+}
+
+// NewReloader loads the configuration at path with LoadConfig, applies it and
+// subscribes w to its topics with the handlers, as Config.Subscribe does. The
+// subscriptions run until ctx is done or w is drained.
+//
+// Set w.Reload to r.Reload to reload on SIGHUP in Run, or call Watch to
+// reload when the file changes.
+func NewReloader(ctx context.Context, w *Worker, path string, handlers map[string]ContextHandler) (*Reloader, error) {
+	r := &Reloader{path: path, worker: w, handlers: handlers, ctx: ctx, current: &Config{}, subs: map[string]*Subscription{}}
+	r.stamp, _ = statFile(path)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.apply(cfg); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Config returns the configuration in effect.
+func (r *Reloader) Config() *Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current
+}
+
+// Reload re-reads the configuration file and applies the differences to the
+// running configuration. On error the running configuration is kept.
+func (r *Reloader) Reload(ctx context.Context) error {
+	r.mu.Lock()
+	r.stamp, _ = statFile(r.path)
+	r.mu.Unlock()
+	cfg, err := LoadConfig(r.path)
+	if err == nil {
+		err = r.apply(cfg)
+	}
+	if err != nil {
+		logAt(ctx, ComponentSubscribe, slog.LevelError, "configuration rejected, keeping the running one", slog.String("path", r.path), slog.Any("error", err))
+		return err
+	}
+	logAt(ctx, ComponentSubscribe, slog.LevelInfo, "configuration reloaded", slog.String("path", r.path))
+	return nil
+}
+
+// Watch checks the configuration file every interval (DefaultWatchInterval
+// when zero) and reloads it when its size or modification time changes. It
+// returns when ctx is done.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		stamp, err := statFile(r.path)
+		r.mu.Lock()
+		changed := err == nil && stamp != r.stamp
+		r.mu.Unlock()
+		if changed {
+			r.Reload(ctx)
+		}
+	}
+}
+
+// apply makes cfg the running configuration. The credentials, TLS settings
+// and API prefix are installed first, so that new subscriptions use them from
+// their first acquire; when a subscription cannot be created or updated, the
+// changes made so far are undone and the running configuration is kept.
+func (r *Reloader) apply(cfg *Config) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	reqs := cfg.Requests()
+	if err := checkHandlers(reqs, r.handlers); err != nil {
+		return err
+	}
+	if err := cfg.apply(r.current); err != nil {
+		return err
+	}
+	var added []*Subscription
+	updated := map[*Subscription]AcquireRequest{}
+	undo := func() {
+		for _, s := range added {
+			s.Stop()
+			delete(r.subs, s.Request().Topic)
+		}
+		for s, req := range updated {
+			s.SetRequest(req)
+		}
+		if err := r.current.apply(cfg); err != nil {
+			logAt(r.ctx, ComponentSubscribe, slog.LevelError, "cannot restore the running configuration", slog.Any("error", err))
+		}
+	}
+	wanted := make(map[string]bool, len(reqs))
+	for _, req := range reqs {
+		wanted[req.Topic] = true
+		if s := r.subs[req.Topic]; s != nil {
+			if prev := s.Request(); !reflect.DeepEqual(prev, req) {
+				if err := s.SetRequest(req); err != nil {
+					undo()
+					return err
+				}
+				updated[s] = prev
+			}
+			continue
+		}
+		s, err := r.worker.Subscribe(r.ctx, req, handlerFor(r.handlers, req.Topic))
+		if err != nil {
+			undo()
+			return err
+		}
+		r.subs[req.Topic] = s
+		added = append(added, s)
+	}
+	for s := range updated {
+		logAt(r.ctx, ComponentSubscribe, slog.LevelInfo, "subscription updated", slog.String(AttrTopic, s.Request().Topic), slog.String("subscription", s.Name()))
+	}
+	for topic, s := range r.subs {
+		if !wanted[topic] {
+			s.Stop()
+			delete(r.subs, topic)
+		}
+	}
+	r.current = cfg
+	return nil
+}
+
+// statFile returns the size and modification time of path.
+func statFile(path string) (fileStamp, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{size: fi.Size(), modTime: fi.ModTime()}, nil
+}
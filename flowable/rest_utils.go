@@ -2,13 +2,20 @@ package flowable
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"sync"
+	"time"
 )
 
 // rest_utils.go centralizes HTTP helpers, default headers, and auth settings used by the package.
 
-// Package-level auth, header, and HTTP client configuration
+// Package-level auth, header, and HTTP client configuration.
+// The variables are kept for compatibility; they are read under configMu, so
+// change them through the Set* functions while requests may be in flight.
 var (
 	AuthUser       = ""
 	AuthPass       = ""
@@ -17,83 +24,226 @@ var (
 		"Accept":       "application/json",
 		"Content-Type": "application/json",
 	}
-	HTTPClient *http.Client = &http.Client{}
+	HTTPClient *http.Client = &http.Client{Transport: NewTransport(DefaultTransportOptions())}
 )
 
+// configMu guards the package-level configuration above and the authenticator.
+var configMu sync.RWMutex
+
 // SetHTTPClient allows callers to override the HTTP client used for REST requests.
 // This is useful for injecting a VCR recorder transport for testing.
 func SetHTTPClient(c *http.Client) {
+	configMu.Lock()
+	defer configMu.Unlock()
 	HTTPClient = c
 }
 
 // SetAuth allows callers to override the basic auth credentials used for REST requests.
+// It is safe to call while requests are in flight, e.g. to rotate credentials.
 func SetAuth(user, pass string) {
+	configMu.Lock()
+	defer configMu.Unlock()
 	AuthUser = user
 	AuthPass = pass
 }
 
 // SetBearerToken allows callers to set a Bearer token for REST requests.
+// It is safe to call while requests are in flight, e.g. to rotate tokens.
 func SetBearerToken(token string) {
+	configMu.Lock()
+	defer configMu.Unlock()
 	BearerToken = token
 }
 
+// Authenticator adds credentials to outgoing REST requests. When one is set
+// with SetAuthenticator it replaces the basic auth and bearer token settings.
+// Implementations must be safe for concurrent use.
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// RefreshableAuthenticator is an Authenticator whose credentials can go stale.
+// When a request is rejected with 401, Invalidate is called and the request
+// is retried once with fresh credentials.
+type RefreshableAuthenticator interface {
+	Authenticator
+	Invalidate()
+}
+
+// authenticator is the Authenticator set by SetAuthenticator, if any.
+var authenticator Authenticator
+
+// SetAuthenticator sets the Authenticator used for REST requests. Pass nil to
+// go back to the SetAuth/SetBearerToken settings.
+func SetAuthenticator(a Authenticator) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	authenticator = a
+}
+
+// currentAuthenticator returns the Authenticator set by SetAuthenticator, if any.
+func currentAuthenticator() Authenticator {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return authenticator
+}
+
+// currentHTTPClient returns the HTTP client set by SetHTTPClient.
+func currentHTTPClient() *http.Client {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return HTTPClient
+}
+
 // SetDefaultHeader sets or overrides a default header key/value for REST requests.
 func SetDefaultHeader(key, value string) {
+	configMu.Lock()
+	defer configMu.Unlock()
 	DefaultHeaders[key] = value
 }
 
 // prepareRequest applies default headers and authentication to an http.Request
-func prepareRequest(req *http.Request) {
+func prepareRequest(req *http.Request) error {
+	configMu.RLock()
 	for k, v := range DefaultHeaders {
 		req.Header.Set(k, v)
 	}
-	if AuthUser != "" || AuthPass != "" {
-		req.SetBasicAuth(AuthUser, AuthPass)
+	auth, user, pass, token := authenticator, AuthUser, AuthPass, BearerToken
+	configMu.RUnlock()
+
+	if auth != nil {
+		return auth.Authenticate(req)
+	}
+	if user != "" || pass != "" {
+		req.SetBasicAuth(user, pass)
 	}
 	// set bearer token if available (won't exist on *http.Request)
-	if BearerToken != "" {
-		req.Header.Set("Authorization", "Bearer "+BearerToken)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
 	}
+	return nil
 }
 
 // restGet performs a GET request to the provided full URL and returns status, body bytes, and error.
-func restGet(fullURL string) (status int, body []byte, err error) {
-	req, err := http.NewRequest("GET", fullURL, nil)
-	if err != nil {
-		return -1, nil, err
-	}
+func restGet(ctx context.Context, kind callKind, fullURL string) (status int, body []byte, err error) {
+	return restDo(ctx, kind, "GET", fullURL, nil)
+}
 
-	prepareRequest(req)
+// restPost performs a POST request to the provided full URL with the given JSON payload.
+func restPost(ctx context.Context, kind callKind, fullURL string, payload []byte) (status int, body []byte, err error) {
+	return restDo(ctx, kind, "POST", fullURL, payload)
+}
 
-	resp, err := HTTPClient.Do(req)
-	if err != nil {
+// restDo sends a request and returns status, body bytes, and error. If the
+// response is a 401 and the authenticator can refresh its credentials, the
+// request is retried once.
+func restDo(ctx context.Context, kind callKind, method, fullURL string, payload []byte) (status int, body []byte, err error) {
+	status, body, err = restDoOnce(ctx, kind, method, fullURL, payload)
+	if err == nil && status == http.StatusUnauthorized {
+		if ra, ok := currentAuthenticator().(RefreshableAuthenticator); ok {
+			ra.Invalidate()
+			return restDoOnce(ctx, kind, method, fullURL, payload)
+		}
+	}
+	return status, body, err
+}
+
+// restDoOnce sends a single request without any retry, bounded by the
+// timeout configured for kind and by the response size limit. 429 and 503
+// responses are returned with a *BackPressureError. When a circuit breaker is
+// installed, the call fails fast with ErrCircuitOpen while the circuit of the
+// node of fullURL is open; calls that fail before being sent are not counted
+// against the node.
+func restDoOnce(ctx context.Context, kind callKind, method, fullURL string, payload []byte) (status int, body []byte, err error) {
+	cb := currentCircuitBreaker()
+	if cb == nil {
+		return sendRequest(ctx, kind, method, fullURL, payload)
+	}
+	node := circuitKey(fullURL)
+	if err := cb.allow(node); err != nil {
 		return -1, nil, err
 	}
-	defer resp.Body.Close()
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return resp.StatusCode, nil, err
+	status, body, err = sendRequest(ctx, kind, method, fullURL, payload)
+	switch {
+	case ctx.Err() != nil || isRequestError(err):
+		cb.record(node, outcomeIgnored)
+	case status >= 500 || (err != nil && status == -1):
+		cb.record(node, outcomeFailure)
+	default:
+		cb.record(node, outcomeSuccess)
 	}
-	return resp.StatusCode, bodyBytes, nil
+	return status, body, err
 }
 
-// restPost performs a POST request to the provided full URL with the given JSON payload.
-func restPost(fullURL string, payload []byte) (status int, body []byte, err error) {
-	req, err := http.NewRequest("POST", fullURL, bytes.NewReader(payload))
+// sendRequest performs the HTTP exchange for restDoOnce.
+func sendRequest(ctx context.Context, kind callKind, method, fullURL string, payload []byte) (status int, body []byte, err error) {
+	if timeout := timeoutFor(kind); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	var reqBody io.Reader
+	if payload != nil {
+		reqBody = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, reqBody)
 	if err != nil {
-		return -1, nil, err
+		return -1, nil, &requestError{err}
 	}
 
-	prepareRequest(req)
+	if err := prepareRequest(req); err != nil {
+		return -1, nil, &requestError{err}
+	}
+	if span := SpanFromContext(ctx); span != nil {
+		if tp := span.TraceParent(); tp != "" {
+			req.Header.Set("traceparent", tp)
+		}
+	}
+	if opts := currentDebug(); opts != nil {
+		start := time.Now()
+		defer func() { traceExchange(opts, req, payload, status, body, err, time.Since(start)) }()
+	}
 
-	resp, err := HTTPClient.Do(req)
+	resp, err := currentHTTPClient().Do(req)
 	if err != nil {
 		return -1, nil, err
 	}
 	defer resp.Body.Close()
-	bodyBytes, err := io.ReadAll(resp.Body)
+	bodyBytes, err := readLimited(resp.Body, responseLimit())
 	if err != nil {
 		return resp.StatusCode, nil, err
 	}
-	return resp.StatusCode, bodyBytes, nil
+	return resp.StatusCode, bodyBytes, backPressureFromResponse(resp)
+}
+
+// requestError is a failure to build or authenticate a request before it was
+// sent. It says nothing about the health of the endpoint.
+type requestError struct {
+	err error
+}
+
+func (e *requestError) Error() string { return e.err.Error() }
+
+func (e *requestError) Unwrap() error { return e.err }
+
+// isRequestError reports whether err is a *requestError.
+func isRequestError(err error) bool {
+	var re *requestError
+	return errors.As(err, &re)
+}
+
+// readLimited reads r completely, failing with ErrResponseTooLarge when it
+// holds more than limit bytes. A limit <= 0 disables the check.
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(r)
+	}
+	b, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > limit {
+		return nil, fmt.Errorf("%w of %d bytes", ErrResponseTooLarge, limit)
+	}
+	return b, nil
 }
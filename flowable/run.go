@@ -0,0 +1,137 @@
+package flowable
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// run.go implements the process lifecycle of a worker binary: it waits for
+// signals, reloads or dumps state on request and drains on shutdown.
+
+// DefaultDrainTimeout is how long Run waits for in-flight jobs on shutdown
+// when Worker.DrainTimeout is zero.
+const DefaultDrainTimeout = 30 * time.Second
+
+// Exit codes returned by Run.
+const (
+	// ExitOK means all in-flight jobs were handled and reported.
+	ExitOK = 0
+	// ExitDrainTimeout means the drain deadline passed with jobs still in flight.
+	ExitDrainTimeout = 2
+	// ExitInterrupted means a second shutdown signal aborted the drain.
+	ExitInterrupted = 130
+)
+
+// Run runs w until ctx is done or the process receives SIGINT or SIGTERM,
+// then drains it for up to w.DrainTimeout and returns the exit code, for use
+// as os.Exit(flowable.Run(ctx, w)). A second SIGINT or SIGTERM during the
+// drain cancels the handlers and returns immediately.
+//
+// SIGHUP calls w.Reload, and SIGUSR1 logs the in-flight jobs and the stacks
+// of all goroutines. Signals that do not exist on the platform are ignored.
+func Run(ctx context.Context, w *Worker) int {
+	sigs := make(chan os.Signal, 4)
+	signal.Notify(sigs, append([]os.Signal{os.Interrupt, syscall.SIGTERM}, extraSignals...)...)
+	defer signal.Stop(sigs)
+	return RunSignals(ctx, w, sigs)
+}
+
+// RunSignals is like Run but reads signals from sigs instead of installing
+// signal handlers, e.g. for tests or when the application handles signals itself.
+func RunSignals(ctx context.Context, w *Worker, sigs <-chan os.Signal) int {
+	for {
+		select {
+		case <-ctx.Done():
+			logAt(ctx, ComponentSubscribe, slog.LevelInfo, "context done, shutting down")
+			return w.shutdown(sigs)
+		case sig := <-sigs:
+			switch {
+			case isReloadSignal(sig):
+				w.reload()
+			case isDumpSignal(sig):
+				w.dump()
+			default:
+				logAt(ctx, ComponentSubscribe, slog.LevelInfo, "shutting down", slog.String("signal", sig.String()))
+				return w.shutdown(sigs)
+			}
+		}
+	}
+}
+
+// shutdown drains w within its drain timeout. A signal on sigs aborts the drain.
+func (w *Worker) shutdown(sigs <-chan os.Signal) int {
+	timeout := w.DrainTimeout
+	if timeout <= 0 {
+		timeout = DefaultDrainTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	interrupted := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-sigs:
+			logAt(ctx, ComponentSubscribe, slog.LevelWarn, "drain interrupted", slog.String("signal", sig.String()))
+			close(interrupted)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	err := w.Drain(ctx)
+	select {
+	case <-interrupted:
+		return ExitInterrupted
+	default:
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		logAt(ctx, ComponentSubscribe, slog.LevelError, "drain timed out", slog.Int("inFlight", len(w.InFlightJobs())), durationAttr(timeout))
+		return ExitDrainTimeout
+	}
+	logAt(ctx, ComponentSubscribe, slog.LevelInfo, "drained")
+	return ExitOK
+}
+
+// reload calls w.Reload and logs the outcome.
+func (w *Worker) reload() {
+	ctx := context.Background()
+	if w.Reload == nil {
+		logAt(ctx, ComponentSubscribe, slog.LevelWarn, "reload requested but no reload function is configured")
+		return
+	}
+	if err := w.Reload(ctx); err != nil {
+		logAt(ctx, ComponentSubscribe, slog.LevelError, "reload failed", slog.Any("error", err))
+		return
+	}
+	logAt(ctx, ComponentSubscribe, slog.LevelInfo, "reloaded")
+}
+
+// dump logs the in-flight jobs and the stacks of all goroutines.
+func (w *Worker) dump() {
+	ctx := context.Background()
+	jobs := w.InFlightJobs()
+	attrs := make([]any, 0, len(jobs))
+	for i, j := range jobs {
+		attrs = append(attrs, slog.Group(strconv.Itoa(i),
+			slog.String(AttrJobId, j.JobId),
+			slog.String(AttrTopic, j.Topic),
+			slog.Time("started", j.Started),
+			slog.Time("lockExpiry", j.LockExpiry)))
+	}
+	logAt(ctx, ComponentSubscribe, slog.LevelInfo, "in-flight jobs", slog.Int("count", len(jobs)), slog.Group("jobs", attrs...))
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	logAt(ctx, ComponentSubscribe, slog.LevelInfo, "goroutine dump", slog.String("stacks", string(buf)))
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package flowable
+
+import "os"
+
+// extraSignals are the signals handled by Run besides SIGINT and SIGTERM.
+var extraSignals []os.Signal
+
+func isReloadSignal(os.Signal) bool { return false }
+
+func isDumpSignal(os.Signal) bool { return false }
@@ -0,0 +1,15 @@
+//go:build unix
+
+package flowable
+
+import (
+	"os"
+	"syscall"
+)
+
+// extraSignals are the signals handled by Run besides SIGINT and SIGTERM.
+var extraSignals = []os.Signal{syscall.SIGHUP, syscall.SIGUSR1}
+
+func isReloadSignal(sig os.Signal) bool { return sig == syscall.SIGHUP }
+
+func isDumpSignal(sig os.Signal) bool { return sig == syscall.SIGUSR1 }
@@ -0,0 +1,140 @@
+package flowable
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// systemd.go implements the sd_notify protocol, so that workers running as
+// systemd services report readiness and are restarted by the watchdog when
+// their poll loops are wedged.
+
+// SDNotify sends state (e.g. "READY=1") to the service manager over the
+// socket named by NOTIFY_SOCKET. It returns false without error when
+// NOTIFY_SOCKET is not set, i.e. the process is not run by systemd.
+func SDNotify(state string) (bool, error) {
+	name := os.Getenv("NOTIFY_SOCKET")
+	if name == "" {
+		return false, nil
+	}
+	if strings.HasPrefix(name, "@") {
+		// Abstract socket namespace.
+		name = "\x00" + name[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: name, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("sd_notify: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, fmt.Errorf("sd_notify: %w", err)
+	}
+	return true, nil
+}
+
+// watchdogInterval returns the watchdog timeout configured by systemd for
+// this process, or 0 when the watchdog is disabled.
+func watchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// NotifySystemd reports the state of w to systemd until ctx is done:
+//
+//   - READY=1 once any subscription has completed a successful acquire,
+//   - STATUS= with the number of subscriptions and in-flight jobs, when it changes,
+//   - WATCHDOG=1 at half the watchdog timeout, but only while every poll
+//     loop makes progress, i.e. none has been stuck in one acquire for longer
+//     than the watchdog timeout, so that systemd restarts a worker whose poll
+//     loops are wedged. Long-running jobs do not withhold the ping.
+//
+// Drain sends STOPPING=1. NotifySystemd returns immediately when
+// NOTIFY_SOCKET is not set.
+func (w *Worker) NotifySystemd(ctx context.Context) {
+	if os.Getenv("NOTIFY_SOCKET") == "" {
+		return
+	}
+	watchdog := watchdogInterval()
+	tick := time.Second
+	if watchdog > 0 {
+		tick = min(tick, watchdog/2)
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	ready := false
+	lastStatus := ""
+	for {
+		r := w.report()
+		if !ready && anyAcquireSucceeded(r) {
+			ready = w.sdNotify(ctx, "READY=1")
+		}
+		inFlight := 0
+		for _, st := range r.Subscriptions {
+			inFlight += st.InFlight
+		}
+		status := fmt.Sprintf("STATUS=%d subscriptions, %d jobs in flight", len(r.Subscriptions), inFlight)
+		if w.Draining() {
+			status += ", draining"
+		}
+		if status != lastStatus && w.sdNotify(ctx, status) {
+			lastStatus = status
+		}
+		if watchdog > 0 {
+			if stuck := stuckLoops(r, watchdog); len(stuck) == 0 {
+				w.sdNotify(ctx, "WATCHDOG=1")
+			} else {
+				logAt(ctx, ComponentSubscribe, slog.LevelError, "poll loops are stuck, withholding watchdog ping", slog.String("subscriptions", strings.Join(stuck, ", ")))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// stuckLoops returns the subscriptions whose poll loop has been busy with one
+// acquire for longer than timeout. Loops that sleep, are paused or wait for a
+// free handler slot are not stuck.
+func stuckLoops(r HealthReport, timeout time.Duration) []string {
+	var stuck []string
+	for _, st := range r.Subscriptions {
+		if !st.BusySince.IsZero() && time.Since(st.BusySince) > timeout {
+			stuck = append(stuck, st.Name)
+		}
+	}
+	return stuck
+}
+
+// anyAcquireSucceeded reports whether a subscription's last acquire succeeded.
+func anyAcquireSucceeded(r HealthReport) bool {
+	for _, st := range r.Subscriptions {
+		if !st.LastAcquire.IsZero() && st.LastAcquireError == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// sdNotify sends state and logs failures; it reports whether the state was sent.
+func (w *Worker) sdNotify(ctx context.Context, state string) bool {
+	sent, err := SDNotify(state)
+	if err != nil {
+		logAt(ctx, ComponentSubscribe, slog.LevelWarn, "sd_notify failed", slog.String("state", state), slog.Any("error", err))
+	}
+	return sent
+}
@@ -0,0 +1,150 @@
+package flowable
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// tls.go builds TLS client configurations for Flowable instances behind an
+// internal CA or requiring mutual TLS.
+
+// TLSOptions describes the TLS settings used to connect to Flowable.
+type TLSOptions struct {
+	// CAFile is a PEM bundle of CA certificates trusted in addition to the system roots.
+	CAFile string
+	// CertFile and KeyFile are a PEM client certificate and key for mutual TLS.
+	// They are re-read when the files change, so rotated certificates are used
+	// for new connections without a restart.
+	CertFile string
+	KeyFile  string
+	// MinVersion defaults to tls.VersionTLS12.
+	MinVersion uint16
+	// ServerName overrides the name used to verify the server certificate.
+	ServerName string
+	// ReloadCheckInterval is how often the client certificate files are
+	// checked for changes; defaults to 10 seconds.
+	ReloadCheckInterval time.Duration
+}
+
+// NewTLSConfig builds a *tls.Config from opts. It fails if the CA bundle or
+// the client key pair cannot be loaded.
+func NewTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: opts.MinVersion,
+		ServerName: opts.ServerName,
+	}
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+	if opts.CAFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: reading CA bundle: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls: no certificates found in CA bundle %s", opts.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if (opts.CertFile == "") != (opts.KeyFile == "") {
+		return nil, errors.New("tls: CertFile and KeyFile must be set together")
+	}
+	if opts.CertFile != "" {
+		r := &certReloader{certFile: opts.CertFile, keyFile: opts.KeyFile, interval: opts.ReloadCheckInterval}
+		if r.interval == 0 {
+			r.interval = 10 * time.Second
+		}
+		if err := r.load(); err != nil {
+			return nil, err
+		}
+		cfg.GetClientCertificate = r.getClientCertificate
+	}
+	return cfg, nil
+}
+
+// ConfigureTLS installs an HTTP client using the TLS settings in opts for all
+// REST requests. The pool settings of the current transport and the timeout
+// of the current client are kept.
+func ConfigureTLS(opts TLSOptions) error {
+	cfg, err := NewTLSConfig(opts)
+	if err != nil {
+		return err
+	}
+	transport, ok := currentHTTPClient().Transport.(*http.Transport)
+	if ok {
+		transport = transport.Clone()
+	} else {
+		transport = NewTransport(DefaultTransportOptions())
+	}
+	transport.TLSClientConfig = cfg
+	SetHTTPClient(&http.Client{Transport: transport, Timeout: currentHTTPClient().Timeout})
+	return nil
+}
+
+// certReloader serves the client certificate, re-reading it when the
+// certificate or key file changes.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	stamps    [2]fileStamp
+	lastCheck time.Time
+}
+
+// getClientCertificate implements tls.Config.GetClientCertificate.
+func (r *certReloader) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.lastCheck) >= r.interval {
+		if err := r.reloadIfChanged(); err != nil {
+			// Keep using the previous certificate; the files may be mid-rotation.
+			logAt(context.Background(), ComponentTLS, slog.LevelWarn, "client certificate reload failed", slog.Any("error", err))
+		}
+	}
+	return r.cert, nil
+}
+
+// load reads the key pair unconditionally.
+func (r *certReloader) load() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reloadIfChanged()
+}
+
+// reloadIfChanged re-reads the key pair if either file changed since the last load.
+func (r *certReloader) reloadIfChanged() error {
+	r.lastCheck = time.Now()
+	var stamps [2]fileStamp
+	for i, f := range []string{r.certFile, r.keyFile} {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return fmt.Errorf("tls: %w", err)
+		}
+		stamps[i] = fileStamp{size: fi.Size(), modTime: fi.ModTime()}
+	}
+	if r.cert != nil && stamps == r.stamps {
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("tls: loading client certificate: %w", err)
+	}
+	r.cert = &cert
+	r.stamps = stamps
+	return nil
+}
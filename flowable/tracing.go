@@ -0,0 +1,183 @@
+package flowable
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// tracing.go provides hooks for distributed tracing. The library starts a
+// span around every acquire, handler invocation and report, and sends the
+// W3C traceparent of the current span with each REST request. The Tracer
+// interface is small enough to be adapted to OpenTelemetry or similar.
+
+// Span names used by the library.
+const (
+	SpanAcquire = "flowable.acquire"
+	SpanHandle  = "flowable.handle"
+	SpanReport  = "flowable.report"
+)
+
+// Span is an operation being traced.
+type Span interface {
+	// SetAttribute records a key/value pair on the span.
+	SetAttribute(key string, value any)
+	// RecordError marks the span as failed with err.
+	RecordError(err error)
+	// End finishes the span.
+	End()
+	// TraceParent returns the W3C traceparent header value identifying the
+	// span, or "" to send no header.
+	TraceParent() string
+}
+
+// Tracer starts spans. The returned context must carry the span so that
+// spans started from it become its children.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+var (
+	tracerMu sync.RWMutex
+	tracer   Tracer
+)
+
+// SetTracer installs t for all subscriptions and REST calls. Pass nil to remove it.
+func SetTracer(t Tracer) {
+	tracerMu.Lock()
+	defer tracerMu.Unlock()
+	tracer = t
+}
+
+type spanKey struct{}
+
+// SpanFromContext returns the library span carried by ctx, or nil. Inside a
+// handler this is the span of the handler invocation.
+func SpanFromContext(ctx context.Context) Span {
+	s, _ := ctx.Value(spanKey{}).(Span)
+	return s
+}
+
+// startSpan starts a span with the installed tracer, or returns a no-op span.
+func startSpan(ctx context.Context, name string) (context.Context, Span) {
+	tracerMu.RLock()
+	t := tracer
+	tracerMu.RUnlock()
+	if t == nil {
+		return ctx, noopSpan{}
+	}
+	ctx, span := t.Start(ctx, name)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(string, any) {}
+func (noopSpan) RecordError(error)        {}
+func (noopSpan) End()                     {}
+func (noopSpan) TraceParent() string      { return "" }
+
+// RecordingTracer is a Tracer keeping finished spans in memory, for tests.
+type RecordingTracer struct {
+	mu    sync.Mutex
+	spans []RecordedSpan
+}
+
+// RecordedSpan is a span finished under a RecordingTracer.
+type RecordedSpan struct {
+	Name       string
+	TraceID    string
+	SpanID     string
+	ParentID   string
+	Attributes map[string]any
+	Err        error
+	Start      time.Time
+	End        time.Time
+}
+
+// NewRecordingTracer returns an empty RecordingTracer.
+func NewRecordingTracer() *RecordingTracer {
+	return &RecordingTracer{}
+}
+
+// Start implements Tracer. Spans started from a context carrying a span of
+// the same tracer are its children.
+func (t *RecordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	s := &recordingSpan{tracer: t, rec: RecordedSpan{
+		Name:       name,
+		TraceID:    randomHex(16),
+		SpanID:     randomHex(8),
+		Attributes: map[string]any{},
+		Start:      time.Now(),
+	}}
+	if parent, ok := SpanFromContext(ctx).(*recordingSpan); ok {
+		s.rec.TraceID = parent.rec.TraceID
+		s.rec.ParentID = parent.rec.SpanID
+	}
+	return ctx, s
+}
+
+// Spans returns the finished spans in the order they ended.
+func (t *RecordingTracer) Spans() []RecordedSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]RecordedSpan(nil), t.spans...)
+}
+
+// Reset discards the recorded spans.
+func (t *RecordingTracer) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.spans = nil
+}
+
+type recordingSpan struct {
+	tracer *RecordingTracer
+	mu     sync.Mutex
+	rec    RecordedSpan
+	ended  bool
+}
+
+func (s *recordingSpan) SetAttribute(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rec.Attributes[key] = value
+}
+
+func (s *recordingSpan) RecordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rec.Err = err
+}
+
+func (s *recordingSpan) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.rec.End = time.Now()
+	rec := s.rec
+	rec.Attributes = make(map[string]any, len(s.rec.Attributes))
+	for k, v := range s.rec.Attributes {
+		rec.Attributes[k] = v
+	}
+	s.mu.Unlock()
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.tracer.spans = append(s.tracer.spans, rec)
+}
+
+func (s *recordingSpan) TraceParent() string {
+	return "00-" + s.rec.TraceID + "-" + s.rec.SpanID + "-01"
+}
+
+// randomHex returns n random bytes in hex.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
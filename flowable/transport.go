@@ -0,0 +1,128 @@
+package flowable
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// transport.go holds the HTTP transport defaults, per-call timeouts and
+// response size limits used by the REST helpers.
+
+// TransportOptions tunes the connection pool of the HTTP transport.
+type TransportOptions struct {
+	DialTimeout         time.Duration
+	KeepAlive           time.Duration
+	TLSHandshakeTimeout time.Duration
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int
+	IdleConnTimeout     time.Duration
+}
+
+// DefaultTransportOptions returns the transport settings used by the default HTTP client.
+func DefaultTransportOptions() TransportOptions {
+	return TransportOptions{
+		DialTimeout:         10 * time.Second,
+		KeepAlive:           30 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 16,
+		IdleConnTimeout:     90 * time.Second,
+	}
+}
+
+// NewTransport returns an *http.Transport configured with opts. Proxy
+// settings are taken from the environment.
+func NewTransport(opts TransportOptions) *http.Transport {
+	dialer := &net.Dialer{Timeout: opts.DialTimeout, KeepAlive: opts.KeepAlive}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   opts.TLSHandshakeTimeout,
+		MaxIdleConns:          opts.MaxIdleConns,
+		MaxIdleConnsPerHost:   opts.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       opts.MaxConnsPerHost,
+		IdleConnTimeout:       opts.IdleConnTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// Timeouts bounds the duration of each kind of REST call, including reading
+// the response body. A zero value disables the timeout for that kind.
+type Timeouts struct {
+	// Acquire applies to acquire calls made by Acquire_jobs and Subscribe.
+	Acquire time.Duration
+	// Report applies to complete, fail, bpmnError and cmmnTerminate calls.
+	Report time.Duration
+	// Query applies to read-only calls such as List_jobs.
+	Query time.Duration
+}
+
+// DefaultTimeouts are the timeouts used unless SetTimeouts is called.
+var DefaultTimeouts = Timeouts{
+	Acquire: 30 * time.Second,
+	Report:  30 * time.Second,
+	Query:   30 * time.Second,
+}
+
+// DefaultMaxResponseBytes is the response body size limit used unless
+// SetMaxResponseBytes is called.
+const DefaultMaxResponseBytes = 10 << 20
+
+// ErrResponseTooLarge is returned when a response body exceeds the configured limit.
+var ErrResponseTooLarge = errors.New("response body exceeds size limit")
+
+// callKind identifies the kind of REST call, selecting its timeout.
+type callKind int
+
+const (
+	callAcquire callKind = iota
+	callReport
+	callQuery
+)
+
+var (
+	limitsMu         sync.RWMutex
+	timeouts               = DefaultTimeouts
+	maxResponseBytes int64 = DefaultMaxResponseBytes
+)
+
+// SetTimeouts sets the per-call timeouts for REST requests.
+func SetTimeouts(t Timeouts) {
+	limitsMu.Lock()
+	defer limitsMu.Unlock()
+	timeouts = t
+}
+
+// SetMaxResponseBytes sets the maximum size of a response body. Zero or a
+// negative value disables the limit.
+func SetMaxResponseBytes(n int64) {
+	limitsMu.Lock()
+	defer limitsMu.Unlock()
+	maxResponseBytes = n
+}
+
+// timeoutFor returns the configured timeout for kind.
+func timeoutFor(kind callKind) time.Duration {
+	limitsMu.RLock()
+	defer limitsMu.RUnlock()
+	switch kind {
+	case callAcquire:
+		return timeouts.Acquire
+	case callReport:
+		return timeouts.Report
+	default:
+		return timeouts.Query
+	}
+}
+
+// responseLimit returns the configured maximum response body size.
+func responseLimit() int64 {
+	limitsMu.RLock()
+	defer limitsMu.RUnlock()
+	return maxResponseBytes
+}
@@ -0,0 +1,298 @@
+package flowable
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// typed_handler.go maps job variables onto Go types and back, so handlers can
+// be written as plain typed functions instead of working on HandlerVariable slices.
+
+// FieldError describes a single variable that could not be decoded into a field.
+type FieldError struct {
+	Field    string
+	Variable string
+	Err      error
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("field %s (variable %q): %v", e.Field, e.Variable, e.Err)
+}
+
+// DecodeError is returned by DecodeVariables and lists every field that failed to decode.
+type DecodeError struct {
+	Fields []FieldError
+}
+
+func (e *DecodeError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Error()
+	}
+	return "cannot decode variables: " + strings.Join(msgs, "; ")
+}
+
+// TypedHandler adapts a typed function to a ContextHandler. The job variables
+// are decoded into In with DecodeVariables, and the returned Out is encoded
+// with EncodeVariables into the variables of a success result. When decoding
+// fails, the job is failed with one message per offending field; when fn
+// returns an error, the outcome is decided by ErrorOutcome.
+func TypedHandler[In, Out any](fn func(ctx context.Context, in In) (Out, error)) ContextHandler {
+	return func(ctx context.Context, status int, body string) (HandlerStatus, *HandlerResult) {
+		if status >= 400 || body == "" {
+			return failResult(fmt.Sprintf("no job to handle (status %d)", status), "")
+		}
+		vars, err := ExtractVariablesFromBody(body)
+		if err != nil {
+			return failResult("cannot read job variables", err.Error())
+		}
+		var in In
+		if err := DecodeVariables(vars, &in); err != nil {
+			return failResult(err.Error(), "")
+		}
+		out, err := fn(ctx, in)
+		if err != nil {
+			return ErrorOutcome(err)
+		}
+		outVars, err := EncodeVariables(out)
+		if err != nil {
+			return failResult("cannot encode result variables", err.Error())
+		}
+		return HandlerSuccess, &HandlerResult{Status: HandlerSuccess, Variables: outVars}
+	}
+}
+
+// failResult builds a HandlerFail result carrying the given message and details.
+func failResult(message, details string) (HandlerStatus, *HandlerResult) {
+	return HandlerFail, &HandlerResult{
+		Status:       HandlerFail,
+		Variables:    []HandlerVariable{},
+		ErrorMessage: message,
+		ErrorDetails: details,
+	}
+}
+
+// DecodeVariables decodes vars into out, which must be a pointer to a struct
+// or to a map with string keys. Struct fields are matched by their json tag
+// name (or field name), case-insensitively like encoding/json; variables
+// without a matching field are ignored. All fields are attempted and every
+// failure is reported in the returned *DecodeError.
+func DecodeVariables(vars []HandlerVariable, out interface{}) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("DecodeVariables: out must be a non-nil pointer, got %T", out)
+	}
+	target := rv.Elem()
+	switch target.Kind() {
+	case reflect.Map:
+		if target.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("DecodeVariables: map key must be string, got %s", target.Type().Key())
+		}
+		if target.IsNil() {
+			target.Set(reflect.MakeMap(target.Type()))
+		}
+		var decodeErr DecodeError
+		for _, v := range vars {
+			elem := reflect.New(target.Type().Elem())
+			if err := decodeVariable(v, elem.Elem()); err != nil {
+				decodeErr.Fields = append(decodeErr.Fields, FieldError{Field: v.Name, Variable: v.Name, Err: err})
+				continue
+			}
+			target.SetMapIndex(reflect.ValueOf(v.Name).Convert(target.Type().Key()), elem.Elem())
+		}
+		if len(decodeErr.Fields) > 0 {
+			return &decodeErr
+		}
+		return nil
+	case reflect.Struct:
+		var decodeErr DecodeError
+		for _, f := range structFields(target.Type()) {
+			v, ok := findVariable(vars, f.name)
+			if !ok {
+				continue
+			}
+			if err := decodeVariable(v, target.FieldByIndex(f.index)); err != nil {
+				decodeErr.Fields = append(decodeErr.Fields, FieldError{Field: f.goName, Variable: v.Name, Err: err})
+			}
+		}
+		if len(decodeErr.Fields) > 0 {
+			return &decodeErr
+		}
+		return nil
+	default:
+		return fmt.Errorf("DecodeVariables: out must point to a struct or map, got %T", out)
+	}
+}
+
+// EncodeVariables converts v into handler variables with Flowable types.
+// v may be a struct (one variable per exported field, honoring json tag names,
+// "-" and omitempty), a map with string keys (one variable per entry, sorted
+// by key), a []HandlerVariable (returned as is) or a pointer to any of these.
+// A nil value yields no variables.
+func EncodeVariables(v interface{}) ([]HandlerVariable, error) {
+	if vars, ok := v.([]HandlerVariable); ok {
+		return vars, nil
+	}
+	result := []HandlerVariable{}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return result, nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return result, nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("EncodeVariables: map key must be string, got %s", rv.Type().Key())
+		}
+		keys := make([]string, 0, rv.Len())
+		for _, k := range rv.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			val := rv.MapIndex(reflect.ValueOf(k).Convert(rv.Type().Key()))
+			if hv, ok := encodeValue(k, val); ok {
+				result = append(result, hv)
+			}
+		}
+	case reflect.Struct:
+		for _, f := range structFields(rv.Type()) {
+			val := rv.FieldByIndex(f.index)
+			if f.omitEmpty && val.IsZero() {
+				continue
+			}
+			if hv, ok := encodeValue(f.name, val); ok {
+				result = append(result, hv)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("EncodeVariables: unsupported type %T", v)
+	}
+	return result, nil
+}
+
+// encodeValue builds the variable for a single value. Nil pointers,
+// interfaces, maps and slices are skipped.
+func encodeValue(name string, val reflect.Value) (HandlerVariable, bool) {
+	for val.Kind() == reflect.Pointer || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return HandlerVariable{}, false
+		}
+		val = val.Elem()
+	}
+	if (val.Kind() == reflect.Map || val.Kind() == reflect.Slice) && val.IsNil() {
+		return HandlerVariable{}, false
+	}
+	return HandlerVariable{Name: name, Type: inferVariableType(val.Interface()), Value: val.Interface()}, true
+}
+
+// decodeVariable stores the value of v into the addressable dst. A string
+// field receives the raw text of a string value, so the JSON document of a
+// "json" variable can be kept as is.
+func decodeVariable(v HandlerVariable, dst reflect.Value) error {
+	if s, ok := v.Value.(string); ok && dst.Kind() == reflect.String {
+		dst.SetString(s)
+		return nil
+	}
+	return assignValue(variableTree(v), dst.Addr().Interface())
+}
+
+// assignValue stores the decoded variable value v into the pointer dst.
+func assignValue(v interface{}, dst interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, dst); err != nil {
+		if te, ok := err.(*json.UnmarshalTypeError); ok {
+			return fmt.Errorf("cannot use %s value as %s", te.Value, te.Type)
+		}
+		return err
+	}
+	return nil
+}
+
+// findVariable returns the variable named name, preferring an exact match
+// over a case-insensitive one.
+func findVariable(vars []HandlerVariable, name string) (HandlerVariable, bool) {
+	for _, v := range vars {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	for _, v := range vars {
+		if strings.EqualFold(v.Name, name) {
+			return v, true
+		}
+	}
+	return HandlerVariable{}, false
+}
+
+type structField struct {
+	goName    string
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+// structFields lists the exported fields of t with their json tag settings.
+func structFields(t reflect.Type) []structField {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, structField{
+			goName:    f.Name,
+			name:      name,
+			index:     f.Index,
+			omitEmpty: strings.Contains(","+opts+",", ",omitempty,"),
+		})
+	}
+	return fields
+}
+
+// inferVariableType returns the Flowable variable type matching a Go value.
+func inferVariableType(v interface{}) string {
+	switch val := v.(type) {
+	case json.Number:
+		if _, err := val.Int64(); err == nil {
+			return "long"
+		}
+		return "bigdecimal"
+	case time.Time:
+		return "date"
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return "integer"
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return "long"
+	case reflect.Float32, reflect.Float64:
+		return "double"
+	default:
+		return "json"
+	}
+}
@@ -0,0 +1,154 @@
+package flowable
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// urls.go builds endpoint URLs from a base URL, which may include a context
+// path such as "/flowable-work", and the external job API prefix.
+
+// DefaultAPIPrefix is the path of the external job REST API below the base URL.
+const DefaultAPIPrefix = "/external-job-api"
+
+// ErrInvalidBaseURL is returned for base URLs that cannot be used to reach Flowable.
+var ErrInvalidBaseURL = errors.New("invalid base URL")
+
+// apiPrefix is the prefix set by SetAPIPrefix, guarded by configMu.
+var apiPrefix = DefaultAPIPrefix
+
+// SetAPIPrefix changes the path of the external job API below the base URL,
+// for deployments that mount it elsewhere. An empty prefix restores
+// DefaultAPIPrefix; "/" puts the API directly at the base URL.
+func SetAPIPrefix(prefix string) {
+	if prefix == "" {
+		prefix = DefaultAPIPrefix
+	}
+	prefix = strings.TrimRight(prefix, "/")
+	if prefix != "" && !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	configMu.Lock()
+	defer configMu.Unlock()
+	apiPrefix = prefix
+}
+
+// currentAPIPrefix returns the prefix set by SetAPIPrefix.
+func currentAPIPrefix() string {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return apiPrefix
+}
+
+// ValidateBaseURL checks that raw is an absolute http or https URL without
+// query or fragment, e.g. "http://localhost:8090/flowable-work". Errors wrap
+// ErrInvalidBaseURL.
+func ValidateBaseURL(raw string) error {
+	_, err := parseBaseURL(raw)
+	return err
+}
+
+// parseBaseURL parses and validates a base URL.
+func parseBaseURL(raw string) (*url.URL, error) {
+	if raw == "" {
+		return nil, fmt.Errorf("%w: empty", ErrInvalidBaseURL)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBaseURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("%w: %q must start with http:// or https://", ErrInvalidBaseURL, raw)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("%w: %q has no host", ErrInvalidBaseURL, raw)
+	}
+	if u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return nil, fmt.Errorf("%w: %q must not contain credentials, a query or a fragment", ErrInvalidBaseURL, raw)
+	}
+	return u, nil
+}
+
+// endpointURL returns the URL of an external job API endpoint below base.
+// Each element of segments is one path segment and is escaped, so job IDs
+// containing '/' or '?' cannot change the endpoint that is called.
+func endpointURL(base string, query url.Values, segments ...string) (string, error) {
+	u, err := parseBaseURL(base)
+	if err != nil {
+		return "", err
+	}
+	escaped := make([]string, len(segments))
+	for i, s := range segments {
+		escaped[i] = url.PathEscape(s)
+	}
+	basePath := strings.TrimRight(u.EscapedPath(), "/")
+	full := u.Scheme + "://" + u.Host + basePath + currentAPIPrefix() + "/" + strings.Join(escaped, "/")
+	if len(query) > 0 {
+		full += "?" + query.Encode()
+	}
+	return full, nil
+}
+
+// JobFilter holds the query parameters supported by the jobs list endpoint.
+// Zero values are not sent.
+type JobFilter struct {
+	Id                string
+	ProcessInstanceId string
+	ExecutionId       string
+	ScopeId           string
+	SubScopeId        string
+	ScopeType         string
+	ElementId         string
+	ElementName       string
+	TenantId          string
+	WithException     bool
+	ExceptionMessage  string
+	Locked            bool
+	Unlocked          bool
+	// Sort is a field name such as "id" or "dueDate"; Order is "asc" or "desc".
+	Sort  string
+	Order string
+	Start int
+	Size  int
+}
+
+// Values returns f as URL query parameters.
+func (f JobFilter) Values() url.Values {
+	q := url.Values{}
+	for _, p := range []struct{ name, value string }{
+		{"id", f.Id},
+		{"processInstanceId", f.ProcessInstanceId},
+		{"executionId", f.ExecutionId},
+		{"scopeId", f.ScopeId},
+		{"subScopeId", f.SubScopeId},
+		{"scopeType", f.ScopeType},
+		{"elementId", f.ElementId},
+		{"elementName", f.ElementName},
+		{"tenantId", f.TenantId},
+		{"exceptionMessage", f.ExceptionMessage},
+		{"sort", f.Sort},
+		{"order", f.Order},
+	} {
+		if p.value != "" {
+			q.Set(p.name, p.value)
+		}
+	}
+	for _, p := range []struct {
+		name string
+		set  bool
+	}{{"withException", f.WithException}, {"locked", f.Locked}, {"unlocked", f.Unlocked}} {
+		if p.set {
+			q.Set(p.name, "true")
+		}
+	}
+	if f.Start > 0 {
+		q.Set("start", strconv.Itoa(f.Start))
+	}
+	if f.Size > 0 {
+		q.Set("size", strconv.Itoa(f.Size))
+	}
+	return q
+}
@@ -0,0 +1,912 @@
+package flowable
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// worker.go runs subscriptions on a Worker, which keeps per-subscription
+// state for health and readiness checks and can drain them on shutdown.
+
+// DefaultStallTimeout is how long a poll loop may stay busy with one acquire
+// before it is considered wedged.
+const DefaultStallTimeout = 5 * time.Minute
+
+// DefaultReadinessFailures is how many acquires in a row must fail before a
+// subscription makes the worker not ready. With 1 the worker is ready only
+// while the last acquire succeeded.
+const DefaultReadinessFailures = 1
+
+// ErrDraining is returned when subscribing to a Worker that is draining.
+var ErrDraining = errors.New("worker is draining")
+
+// Worker runs subscriptions and tracks their state.
+type Worker struct {
+	// StallTimeout defaults to DefaultStallTimeout.
+	StallTimeout time.Duration
+	// ReadinessFailures defaults to DefaultReadinessFailures. Raising it
+	// deliberately departs from "ready while the last acquire succeeded", so
+	// that e.g. a single 429 does not take the worker out of rotation.
+	ReadinessFailures int
+	// AdminToken must be sent as a bearer token with the requests of
+	// AdminHandler that change the worker. While it is empty those requests
+	// are refused.
+	AdminToken string
+	// DrainTimeout bounds the drain performed by Run on shutdown (default DefaultDrainTimeout).
+	DrainTimeout time.Duration
+	// Reload is called by Run on SIGHUP, e.g. to re-read the configuration.
+	Reload func(ctx context.Context) error
+
+	mu       sync.Mutex
+	subs     []*Subscription
+	draining bool
+	wg       sync.WaitGroup
+}
+
+// NewWorker returns a Worker without subscriptions.
+func NewWorker() *Worker {
+	return &Worker{}
+}
+
+// DefaultWorker runs the subscriptions started by Subscribe and SubscribeContext.
+var DefaultWorker = NewWorker()
+
+// Subscription is one poll loop of a Worker.
+type Subscription struct {
+	worker  *Worker
+	name    string
+	topic   string
+	handler ContextHandler
+
+	// runCtx is passed to handlers; acquireCtx additionally ends when the
+	// subscription stops acquiring, e.g. during a drain.
+	runCtx      context.Context
+	cancel      context.CancelFunc
+	acquireCtx  context.Context
+	stopAcquire context.CancelFunc
+	done        chan struct{}
+
+	// jobs counts the handler goroutines of the subscription.
+	jobs sync.WaitGroup
+
+	mu sync.Mutex
+	// req and nodes are replaced by SetRequest; jobs keep the ones they were acquired with.
+	req               AcquireRequest
+	nodes             *endpointPool
+	busySince         time.Time
+	lastAcquire       time.Time
+	lastAcquireStatus int
+	lastAcquireErr    error
+	acquireFailures   int
+	paused            bool
+	concurrency       int
+	active            int
+	inFlight          map[*InFlightJob]struct{}
+	// changed is closed and replaced whenever paused, concurrency or active changes.
+	changed chan struct{}
+}
+
+// InFlightJob describes a job whose handler is running or whose result is being reported.
+type InFlightJob struct {
+	JobId             string    `json:"jobId"`
+	ProcessInstanceId string    `json:"processInstanceId,omitempty"`
+	Topic             string    `json:"topic"`
+	Subscription      string    `json:"subscription"`
+	Started           time.Time `json:"started"`
+	// LockExpiry is the lockExpirationTime sent by the server, or the acquire
+	// time plus the LockDuration of the subscription; zero if neither is known.
+	LockExpiry time.Time `json:"lockExpiry,omitzero"`
+}
+
+// Subscribe validates req and starts a poll loop for it, which runs until ctx
+// is done or the worker is drained.
+func (w *Worker) Subscribe(ctx context.Context, req AcquireRequest, handler ContextHandler) (*Subscription, error) {
+	s, err := w.add(ctx, req, handler)
+	if err != nil {
+		return nil, err
+	}
+	go s.run()
+	return s, nil
+}
+
+// add validates req and registers a new subscription.
+func (w *Worker) add(ctx context.Context, req AcquireRequest, handler ContextHandler) (*Subscription, error) {
+	for _, u := range baseURLs(req) {
+		if err := ValidateBaseURL(u); err != nil {
+			return nil, err
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.draining {
+		return nil, ErrDraining
+	}
+	s := &Subscription{
+		worker:      w,
+		topic:       req.Topic,
+		req:         req,
+		handler:     handler,
+		nodes:       holdPool(req),
+		done:        make(chan struct{}),
+		concurrency: max(req.Concurrency, 1),
+		inFlight:    map[*InFlightJob]struct{}{},
+		changed:     make(chan struct{}),
+	}
+	s.runCtx, s.cancel = context.WithCancel(ctx)
+	s.acquireCtx, s.stopAcquire = context.WithCancel(s.runCtx)
+	s.name = w.uniqueName(req.Topic)
+	w.subs = append(w.subs, s)
+	w.wg.Add(1)
+	return s, nil
+}
+
+// uniqueName returns topic, or topic#2, topic#3, ... if a running
+// subscription already has that name. w.mu must be held.
+func (w *Worker) uniqueName(topic string) string {
+	taken := make(map[string]bool, len(w.subs))
+	for _, s := range w.subs {
+		taken[s.name] = true
+	}
+	name := topic
+	for n := 2; taken[name]; n++ {
+		name = topic + "#" + strconv.Itoa(n)
+	}
+	return name
+}
+
+// remove unregisters s once its loop has ended.
+func (w *Worker) remove(s *Subscription) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for i, sub := range w.subs {
+		if sub == s {
+			w.subs = append(w.subs[:i:i], w.subs[i+1:]...)
+			break
+		}
+	}
+	s.mu.Lock()
+	releasePool(s.nodes)
+	s.mu.Unlock()
+	w.wg.Done()
+}
+
+// Subscriptions returns the running subscriptions.
+func (w *Worker) Subscriptions() []*Subscription {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]*Subscription(nil), w.subs...)
+}
+
+// Draining reports whether Drain has been called.
+func (w *Worker) Draining() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.draining
+}
+
+// ErrUnknownSubscription is returned for names that match no subscription.
+var ErrUnknownSubscription = errors.New("unknown subscription")
+
+// find returns the subscription called name, or all subscriptions of the topic name.
+func (w *Worker) find(name string) ([]*Subscription, error) {
+	var byTopic []*Subscription
+	for _, s := range w.Subscriptions() {
+		if s.name == name {
+			return []*Subscription{s}, nil
+		}
+		if s.topic == name {
+			byTopic = append(byTopic, s)
+		}
+	}
+	if len(byTopic) == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSubscription, name)
+	}
+	return byTopic, nil
+}
+
+// Pause stops the subscription called name, or all subscriptions of the
+// topic name, from acquiring jobs. Jobs already acquired are still handled.
+func (w *Worker) Pause(name string) error {
+	subs, err := w.find(name)
+	for _, s := range subs {
+		s.Pause()
+	}
+	return err
+}
+
+// Resume lets subscriptions paused by Pause acquire jobs again.
+func (w *Worker) Resume(name string) error {
+	subs, err := w.find(name)
+	for _, s := range subs {
+		s.Resume()
+	}
+	return err
+}
+
+// SetConcurrency changes the number of jobs handled in parallel by the
+// subscription called name, or by each subscription of the topic name.
+func (w *Worker) SetConcurrency(name string, n int) error {
+	if n < 1 {
+		return fmt.Errorf("concurrency must be at least 1, got %d", n)
+	}
+	subs, err := w.find(name)
+	for _, s := range subs {
+		s.SetConcurrency(n)
+	}
+	return err
+}
+
+// InFlightJobs returns the jobs currently being handled or reported, oldest first.
+func (w *Worker) InFlightJobs() []InFlightJob {
+	var jobs []InFlightJob
+	for _, s := range w.Subscriptions() {
+		jobs = append(jobs, s.InFlightJobs()...)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Started.Before(jobs[j].Started) })
+	return jobs
+}
+
+// Drain stops all subscriptions from acquiring new jobs and waits until the
+// jobs already acquired have been handled and reported. If ctx ends first,
+// the handlers' contexts are cancelled and ctx.Err() is returned. The worker
+// reports not ready from the moment Drain is called, and STOPPING=1 is sent
+// to systemd when running as a service.
+func (w *Worker) Drain(ctx context.Context) error {
+	w.mu.Lock()
+	w.draining = true
+	subs := append([]*Subscription(nil), w.subs...)
+	w.mu.Unlock()
+	logAt(ctx, ComponentSubscribe, slog.LevelInfo, "draining", slog.Int("subscriptions", len(subs)))
+	w.sdNotify(ctx, "STOPPING=1")
+	for _, s := range subs {
+		s.stopAcquire()
+	}
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		for _, s := range subs {
+			s.cancel()
+		}
+		return ctx.Err()
+	}
+}
+
+// Name identifies the subscription within its worker: the topic, followed by
+// "#2", "#3"... for further subscriptions to the same topic.
+func (s *Subscription) Name() string { return s.name }
+
+// Request returns the acquire parameters of the subscription.
+func (s *Subscription) Request() AcquireRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.req
+}
+
+// settings returns the acquire parameters and the nodes used by the next acquire.
+func (s *Subscription) settings() (AcquireRequest, *endpointPool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.req, s.nodes
+}
+
+// SetRequest changes the acquire parameters of the subscription, e.g. on a
+// configuration reload. The new parameters are used from the next acquire on,
+// after the current sleep; jobs already acquired keep the old ones. The
+// concurrency is changed too when req.Concurrency differs from the current
+// request. The topic cannot be changed.
+func (s *Subscription) SetRequest(req AcquireRequest) error {
+	for _, u := range baseURLs(req) {
+		if err := ValidateBaseURL(u); err != nil {
+			return err
+		}
+	}
+	nodes := holdPool(req)
+	var err error
+	s.update(func() {
+		if req.Topic != s.topic {
+			err = fmt.Errorf("flowable: cannot change the topic of subscription %s to %q", s.name, req.Topic)
+			return
+		}
+		if req.Concurrency != s.req.Concurrency {
+			s.concurrency = max(req.Concurrency, 1)
+		}
+		s.req, s.nodes, nodes = req, nodes, s.nodes
+	})
+	// Release the pool that is no longer used, or the new one on error.
+	releasePool(nodes)
+	return err
+}
+
+// Stop makes the subscription stop acquiring jobs. The jobs already acquired
+// are still handled and reported, after which Done is closed.
+func (s *Subscription) Stop() {
+	s.stopAcquire()
+	logAt(s.runCtx, ComponentSubscribe, slog.LevelInfo, "subscription stopped", slog.String(AttrTopic, s.topic), slog.String("subscription", s.name))
+}
+
+// Done is closed when the poll loop has ended.
+func (s *Subscription) Done() <-chan struct{} { return s.done }
+
+// run is the poll loop: it acquires jobs, hands each one to the handler and
+// reports the result, until the subscription stops acquiring. Up to the
+// configured concurrency jobs are handled in parallel.
+func (s *Subscription) run() {
+	defer func() {
+		s.jobs.Wait()
+		s.cancel()
+		s.worker.remove(s)
+		close(s.done)
+	}()
+	for s.acquireCtx.Err() == nil {
+		// Only acquire while not paused and a handler slot is free, and no
+		// more jobs than there are free slots, so that no acquired job waits
+		// for a slot while its lock runs down.
+		free := s.waitForCapacity()
+		if free == 0 {
+			return
+		}
+		req, nodes := s.settings()
+		if req.NumberOfTasks > free {
+			req.NumberOfTasks = free
+		}
+		s.setBusy(true)
+		start := time.Now()
+		// An acquire in progress is not aborted by a drain, so that jobs
+		// locked by the server are still handled.
+		_, body, status, node, err := acquireJobs(s.runCtx, nodes, req)
+		if s.runCtx.Err() != nil {
+			return
+		}
+		s.recordAcquire(status, err)
+		if errors.Is(err, ErrCircuitOpen) {
+			// Flowable is considered down: wait for the circuit breaker instead of polling.
+			s.setBusy(false)
+			waitForCircuit(s.acquireCtx)
+			continue
+		}
+		metricPolls.add(1, req.Topic)
+		metricAcquireDuration.observe(time.Since(start).Seconds(), req.Topic)
+		if err != nil {
+			metricAcquireErrors.add(1, req.Topic)
+		}
+		var bp *BackPressureError
+		if errors.As(err, &bp) {
+			pause := max(bp.RetryAfter, req.Interval)
+			notifyBackPressure(s.runCtx, BackPressureEvent{Operation: "acquire", URL: node, Status: bp.Status, Pause: pause})
+			s.sleep(pause)
+			continue
+		}
+		if err != nil {
+			logAt(s.runCtx, ComponentAcquire, slog.LevelWarn, "acquire failed", slog.String(AttrTopic, req.Topic), slog.Int("status", status), slog.Any("error", err))
+			// If acquire failed (including parse errors), treat as status 500 and pass the raw body if available
+			resStatus, resObj := s.handler(s.runCtx, 500, "")
+			handle_worker_response(s.runCtx, nodes, node, req.WorkerId, "", resStatus, resObj, time.Time{})
+			s.sleep(req.Interval)
+			continue
+		}
+		// Re-read the response as raw messages so each job is handed to the handler
+		// exactly as the server sent it (no float64 round trip on numeric fields).
+		var jobs []json.RawMessage
+		if err := json.Unmarshal([]byte(body), &jobs); err != nil {
+			resStatus, resObj := s.handler(s.runCtx, 500, "")
+			handle_worker_response(s.runCtx, nodes, node, req.WorkerId, "", resStatus, resObj, time.Time{})
+			s.sleep(req.Interval)
+			continue
+		}
+		if len(jobs) == 0 {
+			// No jobs, wait and poll again
+			metricEmptyPolls.add(1, req.Topic)
+			s.sleep(req.Interval)
+			continue
+		}
+		metricAcquired.add(float64(len(jobs)), req.Topic)
+		metricInFlight.add(float64(len(jobs)), req.Topic)
+		acquired := time.Now()
+		// Jobs found, hand each one to the handler as soon as a slot is free.
+		// The whole batch is handled even when the subscription is paused or
+		// drained meanwhile, since the server has locked these jobs for us.
+		for _, job := range jobs {
+			s.takeSlot()
+			s.jobs.Add(1)
+			go func() {
+				defer s.jobs.Done()
+				defer s.releaseSlot()
+				s.handleJob(req, nodes, node, status, job, acquired)
+			}()
+		}
+		s.sleep(req.Interval)
+	}
+}
+
+// handleJob calls the handler for one job acquired with req and reports the result to node.
+func (s *Subscription) handleJob(req AcquireRequest, nodes *endpointPool, node string, status int, job json.RawMessage, acquired time.Time) {
+	jobId, processInstanceId := extractJobFields(job)
+	entry := &InFlightJob{
+		JobId:             jobId,
+		ProcessInstanceId: processInstanceId,
+		Topic:             req.Topic,
+		Subscription:      s.name,
+		Started:           time.Now(),
+		LockExpiry:        lockExpiry(job, acquired, req.LockDuration),
+	}
+	s.trackJob(entry, true)
+	defer s.trackJob(entry, false)
+
+	jobCtx, span := startSpan(jobContext(s.runCtx, req, jobId, processInstanceId), SpanHandle)
+	span.SetAttribute(AttrTopic, req.Topic)
+	span.SetAttribute(AttrJobId, jobId)
+	span.SetAttribute(AttrProcessInstanceId, processInstanceId)
+	start := time.Now()
+	resStatus, resObj := s.handler(jobCtx, status, string(job))
+	elapsed := time.Since(start)
+	span.SetAttribute(AttrOutcome, string(resStatus))
+	span.End()
+	metricHandlerDuration.observe(elapsed.Seconds(), req.Topic)
+	logAt(jobCtx, ComponentHandler, slog.LevelInfo, "job handled", slog.String(AttrOutcome, string(resStatus)), durationAttr(elapsed))
+	// Delegate result handling to helper
+	handle_worker_response(jobCtx, nodes, node, req.WorkerId, jobId, resStatus, resObj, entry.LockExpiry)
+	metricInFlight.add(-1, req.Topic)
+}
+
+// lockExpiry returns the lockExpirationTime of job, or acquired plus lockDuration.
+func lockExpiry(job json.RawMessage, acquired time.Time, lockDuration string) time.Time {
+	var meta struct {
+		LockExpirationTime string `json:"lockExpirationTime"`
+	}
+	if json.Unmarshal(job, &meta) == nil && meta.LockExpirationTime != "" {
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.000Z0700"} {
+			if t, err := time.Parse(layout, meta.LockExpirationTime); err == nil {
+				return t
+			}
+		}
+	}
+	if d, err := parseISODuration(lockDuration); err == nil {
+		return acquired.Add(d)
+	}
+	return time.Time{}
+}
+
+// isoDurationPattern matches the ISO-8601 durations used by Flowable, e.g. "PT10M" or "P1DT2H".
+var isoDurationPattern = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// parseISODuration parses a day-time ISO-8601 duration such as "PT10M".
+func parseISODuration(s string) (time.Duration, error) {
+	m := isoDurationPattern.FindStringSubmatch(s)
+	if m == nil || s == "P" || strings.HasSuffix(s, "T") {
+		return 0, fmt.Errorf("invalid ISO-8601 duration %q", s)
+	}
+	var d time.Duration
+	for i, unit := range []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if m[i+1] == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(m[i+1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid ISO-8601 duration %q", s)
+		}
+		d += time.Duration(v * float64(unit))
+	}
+	return d, nil
+}
+
+// Pause stops the subscription from acquiring jobs until Resume is called.
+func (s *Subscription) Pause() {
+	s.update(func() { s.paused = true })
+	logAt(s.runCtx, ComponentSubscribe, slog.LevelInfo, "subscription paused", slog.String(AttrTopic, s.topic), slog.String("subscription", s.name))
+}
+
+// Resume lets a paused subscription acquire jobs again.
+func (s *Subscription) Resume() {
+	s.update(func() { s.paused = false })
+	logAt(s.runCtx, ComponentSubscribe, slog.LevelInfo, "subscription resumed", slog.String(AttrTopic, s.topic), slog.String("subscription", s.name))
+}
+
+// SetConcurrency changes the number of jobs handled in parallel; values below 1 are treated as 1.
+func (s *Subscription) SetConcurrency(n int) {
+	s.update(func() { s.concurrency = max(n, 1) })
+}
+
+// InFlightJobs returns the jobs of s currently being handled or reported.
+func (s *Subscription) InFlightJobs() []InFlightJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]InFlightJob, 0, len(s.inFlight))
+	for j := range s.inFlight {
+		jobs = append(jobs, *j)
+	}
+	return jobs
+}
+
+// update applies fn under s.mu and wakes up the poll loop.
+func (s *Subscription) update(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn()
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// waitForCapacity blocks while the subscription is paused or all handler
+// slots are taken. It returns the number of free slots, or 0 when the
+// subscription stops acquiring.
+func (s *Subscription) waitForCapacity() int {
+	s.setBusy(false)
+	for {
+		s.mu.Lock()
+		free, changed := s.concurrency-s.active, s.changed
+		if s.paused {
+			free = 0
+		}
+		s.mu.Unlock()
+		if free > 0 {
+			return free
+		}
+		select {
+		case <-changed:
+		case <-s.acquireCtx.Done():
+			return 0
+		}
+	}
+}
+
+// takeSlot waits for a free handler slot. Once the handlers' context is
+// cancelled it no longer waits, so that the rest of the batch fails fast.
+func (s *Subscription) takeSlot() {
+	for {
+		s.mu.Lock()
+		if s.active < s.concurrency || s.runCtx.Err() != nil {
+			s.active++
+			s.mu.Unlock()
+			return
+		}
+		changed := s.changed
+		s.mu.Unlock()
+		select {
+		case <-changed:
+		case <-s.runCtx.Done():
+		}
+	}
+}
+
+// releaseSlot frees a slot taken by takeSlot.
+func (s *Subscription) releaseSlot() {
+	s.update(func() { s.active-- })
+}
+
+// trackJob adds or removes j from the in-flight jobs.
+func (s *Subscription) trackJob(j *InFlightJob, add bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if add {
+		s.inFlight[j] = struct{}{}
+	} else {
+		delete(s.inFlight, j)
+	}
+}
+
+// sleep waits for d while the subscription is acquiring; the loop counts as idle meanwhile.
+func (s *Subscription) sleep(d time.Duration) {
+	s.setBusy(false)
+	sleepContext(s.acquireCtx, d)
+}
+
+// setBusy marks the start or the end of a unit of work of the poll loop.
+func (s *Subscription) setBusy(busy bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if busy {
+		s.busySince = time.Now()
+	} else {
+		s.busySince = time.Time{}
+	}
+}
+
+// recordAcquire stores the result of the last acquire.
+func (s *Subscription) recordAcquire(status int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastAcquire = time.Now()
+	s.lastAcquireStatus = status
+	s.lastAcquireErr = err
+	if err != nil {
+		s.acquireFailures++
+	} else {
+		s.acquireFailures = 0
+	}
+}
+
+// SubscriptionStatus is a snapshot of the state of a subscription.
+type SubscriptionStatus struct {
+	Name              string    `json:"name"`
+	Topic             string    `json:"topic"`
+	Acquiring         bool      `json:"acquiring"`
+	LastAcquire       time.Time `json:"lastAcquire,omitzero"`
+	LastAcquireStatus int       `json:"lastAcquireStatus,omitempty"`
+	LastAcquireError  string    `json:"lastAcquireError,omitempty"`
+	// AcquireFailures is the number of acquires in a row that failed.
+	AcquireFailures int `json:"acquireFailures,omitempty"`
+	// BusySince is when the current acquire started; zero while idle.
+	BusySince   time.Time `json:"busySince,omitzero"`
+	Paused      bool      `json:"paused"`
+	Concurrency int       `json:"concurrency"`
+	InFlight    int       `json:"inFlight"`
+}
+
+// Status returns a snapshot of the state of s.
+func (s *Subscription) Status() SubscriptionStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := SubscriptionStatus{
+		Name:              s.name,
+		Topic:             s.req.Topic,
+		Acquiring:         s.acquireCtx.Err() == nil,
+		LastAcquire:       s.lastAcquire,
+		LastAcquireStatus: s.lastAcquireStatus,
+		AcquireFailures:   s.acquireFailures,
+		BusySince:         s.busySince,
+		Paused:            s.paused,
+		Concurrency:       s.concurrency,
+		InFlight:          len(s.inFlight),
+	}
+	if s.lastAcquireErr != nil {
+		st.LastAcquireError = s.lastAcquireErr.Error()
+	}
+	return st
+}
+
+// SubscriptionConfig is the configuration of a subscription as shown by the admin API.
+type SubscriptionConfig struct {
+	URLs            []string `json:"urls"`
+	LockDuration    string   `json:"lockDuration"`
+	NumberOfTasks   int      `json:"numberOfTasks"`
+	NumberOfRetries int      `json:"numberOfRetries"`
+	WorkerId        string   `json:"workerId"`
+	ScopeType       string   `json:"scopeType,omitempty"`
+	Interval        string   `json:"interval"`
+}
+
+// SubscriptionInfo combines the state and the configuration of a subscription.
+type SubscriptionInfo struct {
+	SubscriptionStatus
+	Config SubscriptionConfig `json:"config"`
+}
+
+// Info returns the state and configuration of s.
+func (s *Subscription) Info() SubscriptionInfo {
+	req := s.Request()
+	return SubscriptionInfo{
+		SubscriptionStatus: s.Status(),
+		Config: SubscriptionConfig{
+			URLs:            baseURLs(req),
+			LockDuration:    req.LockDuration,
+			NumberOfTasks:   req.NumberOfTasks,
+			NumberOfRetries: req.NumberOfRetries,
+			WorkerId:        req.WorkerId,
+			ScopeType:       req.ScopeType,
+			Interval:        req.Interval.String(),
+		},
+	}
+}
+
+// HealthReport is the result of a health or readiness check.
+type HealthReport struct {
+	OK            bool                 `json:"ok"`
+	Problems      []string             `json:"problems,omitempty"`
+	Subscriptions []SubscriptionStatus `json:"subscriptions"`
+}
+
+// Health reports whether the poll loops are alive: it fails when an acquire
+// has been running for longer than StallTimeout. Busy handlers do not count,
+// since restarting the process would not help them.
+func (w *Worker) Health() HealthReport {
+	stall := w.StallTimeout
+	if stall <= 0 {
+		stall = DefaultStallTimeout
+	}
+	r := w.report()
+	for _, st := range r.Subscriptions {
+		if !st.BusySince.IsZero() && time.Since(st.BusySince) > stall {
+			r.Problems = append(r.Problems, fmt.Sprintf("%s: acquire running for %s", st.Name, time.Since(st.BusySince).Round(time.Second)))
+		}
+	}
+	r.OK = len(r.Problems) == 0
+	return r
+}
+
+// Readiness reports whether the worker is able to process jobs: it has
+// subscriptions, is not draining, the circuit breaker is not open, no
+// acquire was rejected with 401 or 403, no subscription has failed
+// ReadinessFailures acquires in a row, and no handler is still running after
+// the lock of its job expired.
+func (w *Worker) Readiness() HealthReport {
+	failures := w.ReadinessFailures
+	if failures <= 0 {
+		failures = DefaultReadinessFailures
+	}
+	r := w.report()
+	if w.Draining() {
+		r.Problems = append(r.Problems, "draining")
+	}
+	if len(r.Subscriptions) == 0 {
+		r.Problems = append(r.Problems, "no subscriptions")
+	}
+	if cb := currentCircuitBreaker(); cb != nil && cb.State() == CircuitOpen {
+		r.Problems = append(r.Problems, "circuit breaker open")
+	}
+	for _, st := range r.Subscriptions {
+		switch {
+		case st.LastAcquire.IsZero():
+			r.Problems = append(r.Problems, st.Name+": no acquire yet")
+		case st.LastAcquireStatus == http.StatusUnauthorized || st.LastAcquireStatus == http.StatusForbidden:
+			r.Problems = append(r.Problems, fmt.Sprintf("%s: authentication failed (status %d)", st.Name, st.LastAcquireStatus))
+		case st.AcquireFailures >= failures:
+			r.Problems = append(r.Problems, fmt.Sprintf("%s: %d acquires in a row failed, last: %s", st.Name, st.AcquireFailures, st.LastAcquireError))
+		}
+	}
+	for _, j := range w.InFlightJobs() {
+		if !j.LockExpiry.IsZero() && time.Now().After(j.LockExpiry) {
+			r.Problems = append(r.Problems, fmt.Sprintf("%s: job %s running for %s, past its lock expiry", j.Subscription, j.JobId, time.Since(j.Started).Round(time.Second)))
+		}
+	}
+	r.OK = len(r.Problems) == 0
+	return r
+}
+
+// report collects the status of all subscriptions.
+func (w *Worker) report() HealthReport {
+	r := HealthReport{Subscriptions: []SubscriptionStatus{}}
+	for _, s := range w.Subscriptions() {
+		r.Subscriptions = append(r.Subscriptions, s.Status())
+	}
+	return r
+}
+
+// AdminHandler returns an http.Handler for operating the worker:
+//
+//	GET  /healthz                             Health (200 or 503)
+//	GET  /readyz                              Readiness (200 or 503)
+//	GET  /metrics                             metrics in Prometheus text format
+//	GET  /subscriptions                       subscriptions with state and configuration
+//	GET  /jobs                                in-flight jobs
+//	POST /subscriptions/{name}/pause          stop acquiring for a subscription or topic
+//	POST /subscriptions/{name}/resume         resume acquiring
+//	POST /subscriptions/{name}/concurrency    set concurrency, body {"concurrency": n}
+//	POST /drain?timeout=30s                   start draining the worker
+//
+// The POST endpoints require AdminToken and are refused with 403 while it
+// is not set.
+func (w *Worker) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(rw http.ResponseWriter, r *http.Request) {
+		writeHealth(rw, w.Health())
+	})
+	mux.HandleFunc("GET /readyz", func(rw http.ResponseWriter, r *http.Request) {
+		writeHealth(rw, w.Readiness())
+	})
+	mux.Handle("GET /metrics", MetricsHandler())
+	mux.HandleFunc("GET /subscriptions", func(rw http.ResponseWriter, r *http.Request) {
+		infos := []SubscriptionInfo{}
+		for _, s := range w.Subscriptions() {
+			infos = append(infos, s.Info())
+		}
+		writeJSON(rw, http.StatusOK, infos)
+	})
+	mux.HandleFunc("GET /jobs", func(rw http.ResponseWriter, r *http.Request) {
+		jobs := w.InFlightJobs()
+		if jobs == nil {
+			jobs = []InFlightJob{}
+		}
+		writeJSON(rw, http.StatusOK, jobs)
+	})
+	mux.HandleFunc("POST /subscriptions/{name}/pause", w.adminAction(func(r *http.Request) error {
+		return w.Pause(r.PathValue("name"))
+	}))
+	mux.HandleFunc("POST /subscriptions/{name}/resume", w.adminAction(func(r *http.Request) error {
+		return w.Resume(r.PathValue("name"))
+	}))
+	mux.HandleFunc("POST /subscriptions/{name}/concurrency", w.adminAction(func(r *http.Request) error {
+		var body struct {
+			Concurrency int `json:"concurrency"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return fmt.Errorf("%w: %v", errBadRequest, err)
+		}
+		if body.Concurrency < 1 {
+			return fmt.Errorf("%w: concurrency must be at least 1", errBadRequest)
+		}
+		return w.SetConcurrency(r.PathValue("name"), body.Concurrency)
+	}))
+	mux.HandleFunc("POST /drain", w.adminAction(func(r *http.Request) error {
+		timeout := 30 * time.Second
+		if v := r.URL.Query().Get("timeout"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("%w: %v", errBadRequest, err)
+			}
+			timeout = d
+		}
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			if err := w.Drain(ctx); err != nil {
+				logAt(ctx, ComponentSubscribe, slog.LevelWarn, "drain did not finish", slog.Any("error", err))
+			}
+		}()
+		return nil
+	}))
+	return mux
+}
+
+// errBadRequest marks admin request errors caused by the request itself.
+var errBadRequest = errors.New("bad request")
+
+// adminAction wraps a state-changing admin endpoint with the token check and
+// maps its error to a status code.
+func (w *Worker) adminAction(fn func(r *http.Request) error) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if w.AdminToken == "" {
+			writeJSON(rw, http.StatusForbidden, map[string]string{"error": "admin actions are disabled: AdminToken is not set"})
+			return
+		}
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(w.AdminToken)) != 1 {
+			writeJSON(rw, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		err := fn(r)
+		switch {
+		case err == nil:
+			writeJSON(rw, http.StatusAccepted, map[string]string{"status": "ok"})
+		case errors.Is(err, ErrUnknownSubscription):
+			writeJSON(rw, http.StatusNotFound, map[string]string{"error": err.Error()})
+		default:
+			writeJSON(rw, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+	}
+}
+
+// writeJSON writes v as a JSON response with the given status code.
+func writeJSON(rw http.ResponseWriter, status int, v any) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	json.NewEncoder(rw).Encode(v)
+}
+
+// writeHealth writes r with the matching status code.
+func writeHealth(rw http.ResponseWriter, r HealthReport) {
+	status := http.StatusOK
+	if !r.OK {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(rw, status, r)
+}
+
+// ServeAdmin starts an HTTP listener on addr serving AdminHandler. It returns
+// once the listener is open; stop it with Shutdown or Close.
+func (w *Worker) ServeAdmin(addr string) (*http.Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	srv := &http.Server{Addr: ln.Addr().String(), Handler: w.AdminHandler(), ReadHeaderTimeout: 10 * time.Second}
+	go srv.Serve(ln)
+	return srv, nil
+}
@@ -1,39 +1,45 @@
 package main
 
 import (
-	"time"
+	"context"
+	"flag"
+	"log"
+	"os"
 
 	"github.com/flowable/flowable-external-client-golang/flowable"
 	"github.com/flowable/flowable-external-client-golang/worker"
 )
 
-// Sample main function to start the Flowable external worker subscription
+// Sample main function to start the Flowable external worker subscriptions
+// described by a configuration file (see worker.example.yaml) and FLOWABLE_*
+// environment variables, e.g. FLOWABLE_PASSWORD.
 
 func main() {
+	configPath := flag.String("config", os.Getenv("FLOWABLE_CONFIG"), "path to the YAML or JSON configuration file")
+	flag.Parse()
 
-	// Configure package-level defaults for auth/headers
-	flowable.SetAuth("admin", "test")
-	// flowable.SetBearerToken("token")
 	// override headers if needed
 	// flowable.SetDefaultHeader("X-My-Header", "value")
 	// Enable logging (default is true)
 	flowable.SetEnableLogging(true)
-	// if using a bearer token
+	// JSON logs for production
+	// flowable.SetLogger(flowable.NewJSONLogger(os.Stdout, slog.LevelInfo))
 
-	// Provide subscription parameters
-	acquireParams := flowable.AcquireRequest{
-		Topic:           "testing",
-		LockDuration:    "PT10M",
-		NumberOfTasks:   1,
-		NumberOfRetries: 5,
-		WorkerId:        "worker1",
-		ScopeType:       "bpmn",
-		URL:             "http://localhost:8090",
-		Interval:        10 * time.Second,
+	// Start the subscriptions to Flowable; every topic uses the sample handler.
+	// Changes to the configuration file are applied without a restart, on
+	// SIGHUP or when the file changes.
+	ctx := context.Background()
+	handlers := map[string]flowable.ContextHandler{
+		"*": worker.ExternalWorker,
 	}
-	// Start the subscription to Flowable
-	go flowable.Subscribe(acquireParams, worker.ExternalWorker)
+	reloader, err := flowable.NewReloader(ctx, flowable.DefaultWorker, *configPath, handlers)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("configuration:\n%s", reloader.Config())
+	flowable.DefaultWorker.Reload = reloader.Reload
+	go reloader.Watch(ctx, 0)
 
-	// Keep the main function running indefinitely
-	select {}
+	// Run until SIGINT/SIGTERM, then finish the in-flight jobs and exit
+	os.Exit(flowable.Run(ctx, flowable.DefaultWorker))
 }
@@ -0,0 +1,244 @@
+package worker_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/flowable/flowable-external-client-golang/flowable"
+)
+
+func adminRequest(t *testing.T, h http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAdmin_ConcurrencyAndInFlightJobs(t *testing.T) {
+	quietLogs(t)
+	var once sync.Once
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/external-job-api/acquire/jobs" {
+			body := `[]`
+			once.Do(func() {
+				body = `[{"id":"job-1","lockExpirationTime":"2030-01-02T03:04:05.000Z"},{"id":"job-2"},{"id":"job-3"}]`
+			})
+			io.WriteString(w, body)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	w := flowable.NewWorker()
+	var running int32
+	release := make(chan struct{})
+	acquired := time.Now()
+	sub, err := w.Subscribe(context.Background(), flowable.AcquireRequest{
+		Topic: "parallel", WorkerId: "w", URL: srv.URL, NumberOfTasks: 3, LockDuration: "PT10M",
+		Interval: 5 * time.Millisecond, Concurrency: 3,
+	}, func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+		atomic.AddInt32(&running, 1)
+		<-release
+		return flowable.HandlerSuccess, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "three parallel handlers", func() bool { return atomic.LoadInt32(&running) == 3 })
+
+	rec := adminRequest(t, w.AdminHandler(), "GET", "/jobs", "", "")
+	var jobs []flowable.InFlightJob
+	if err := json.Unmarshal(rec.Body.Bytes(), &jobs); err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 3 {
+		t.Fatalf("expected three in-flight jobs, got %s", rec.Body.String())
+	}
+	for _, j := range jobs {
+		if j.Topic != "parallel" || j.Subscription != "parallel" || j.Started.IsZero() {
+			t.Errorf("unexpected in-flight job %+v", j)
+		}
+		want := acquired.Add(10 * time.Minute)
+		if j.JobId == "job-1" {
+			want = time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+			if !j.LockExpiry.Equal(want) {
+				t.Errorf("expected the lock expiry sent by the server, got %v", j.LockExpiry)
+			}
+		} else if j.LockExpiry.Before(want) || j.LockExpiry.After(want.Add(time.Second)) {
+			t.Errorf("expected the lock expiry from the lock duration, got %v", j.LockExpiry)
+		}
+	}
+	if st := sub.Status(); st.InFlight != 3 || st.Concurrency != 3 {
+		t.Errorf("unexpected status %+v", st)
+	}
+	close(release)
+	if err := w.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAdmin_AcquireIsCappedByFreeSlots(t *testing.T) {
+	quietLogs(t)
+	requested := make(chan int, 10)
+	var once sync.Once
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/external-job-api/acquire/jobs" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		var req flowable.AcquireRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		select {
+		case requested <- req.NumberOfTasks:
+		default:
+		}
+		body := `[]`
+		once.Do(func() { body = `[{"id":"job-1"}]` })
+		io.WriteString(w, body)
+	}))
+	defer srv.Close()
+
+	w := flowable.NewWorker()
+	release := make(chan struct{})
+	_, err := w.Subscribe(context.Background(), flowable.AcquireRequest{
+		Topic: "capped", WorkerId: "w", URL: srv.URL, NumberOfTasks: 5, Interval: 5 * time.Millisecond, Concurrency: 2,
+	}, func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+		<-release
+		return flowable.HandlerSuccess, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := <-requested; n != 2 {
+		t.Errorf("expected the first acquire to ask for 2 jobs, got %d", n)
+	}
+	if n := <-requested; n != 1 {
+		t.Errorf("expected 1 job while one slot is taken, got %d", n)
+	}
+	close(release)
+	if err := w.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAdmin_PauseResumeAndConcurrency(t *testing.T) {
+	quietLogs(t)
+	var acquires int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&acquires, 1)
+		io.WriteString(w, `[]`)
+	}))
+	defer srv.Close()
+
+	w := flowable.NewWorker()
+	w.AdminToken = "s3cret"
+	h := w.AdminHandler()
+	ctx, cancel := context.WithCancel(context.Background())
+	sub, err := w.Subscribe(ctx, flowable.AcquireRequest{Topic: "orders", WorkerId: "w", URL: srv.URL, LockDuration: "PT1M", Interval: 2 * time.Millisecond},
+		func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+			return flowable.HandlerSuccess, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cancel()
+		<-sub.Done()
+	}()
+	waitFor(t, "a first acquire", func() bool { return atomic.LoadInt32(&acquires) > 0 })
+
+	if rec := adminRequest(t, flowable.NewWorker().AdminHandler(), "POST", "/subscriptions/orders/pause", "", ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 without AdminToken, got %d", rec.Code)
+	}
+	if rec := adminRequest(t, h, "POST", "/subscriptions/orders/pause", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", rec.Code)
+	}
+	if rec := adminRequest(t, h, "POST", "/subscriptions/orders/pause", "s3cret", ""); rec.Code != http.StatusAccepted {
+		t.Fatalf("pause: %d %s", rec.Code, rec.Body.String())
+	}
+	waitFor(t, "the pause", func() bool { return sub.Status().Paused })
+	time.Sleep(10 * time.Millisecond)
+	paused := atomic.LoadInt32(&acquires)
+	time.Sleep(30 * time.Millisecond)
+	if n := atomic.LoadInt32(&acquires); n != paused {
+		t.Fatalf("expected no acquires while paused, got %d more", n-paused)
+	}
+	if rec := adminRequest(t, h, "POST", "/subscriptions/orders/resume", "s3cret", ""); rec.Code != http.StatusAccepted {
+		t.Fatalf("resume: %d %s", rec.Code, rec.Body.String())
+	}
+	waitFor(t, "acquires to resume", func() bool { return atomic.LoadInt32(&acquires) > paused })
+
+	if rec := adminRequest(t, h, "POST", "/subscriptions/orders/concurrency", "s3cret", `{"concurrency":4}`); rec.Code != http.StatusAccepted {
+		t.Fatalf("concurrency: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := adminRequest(t, h, "POST", "/subscriptions/orders/concurrency", "s3cret", `{"concurrency":0}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for concurrency 0, got %d", rec.Code)
+	}
+	if rec := adminRequest(t, h, "POST", "/subscriptions/missing/pause", "s3cret", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown subscription, got %d", rec.Code)
+	}
+
+	rec := adminRequest(t, h, "GET", "/subscriptions", "", "")
+	var infos []flowable.SubscriptionInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &infos); err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || infos[0].Concurrency != 4 || infos[0].Paused || infos[0].Config.LockDuration != "PT1M" || infos[0].Config.URLs[0] != srv.URL {
+		t.Fatalf("unexpected subscriptions %s", rec.Body.String())
+	}
+
+	if rec := adminRequest(t, h, "POST", "/drain?timeout=1s", "s3cret", ""); rec.Code != http.StatusAccepted {
+		t.Fatalf("drain: %d %s", rec.Code, rec.Body.String())
+	}
+	waitFor(t, "the drain", func() bool { return w.Draining() })
+	<-sub.Done()
+}
+
+func TestAdmin_SubscriptionNamesAreReusedAfterRemoval(t *testing.T) {
+	quietLogs(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `[]`)
+	}))
+	defer srv.Close()
+
+	w := flowable.NewWorker()
+	subscribe := func() (*flowable.Subscription, context.CancelFunc) {
+		ctx, cancel := context.WithCancel(context.Background())
+		sub, err := w.Subscribe(ctx, flowable.AcquireRequest{Topic: "orders", WorkerId: "w", URL: srv.URL, Interval: time.Hour},
+			func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+				return flowable.HandlerSuccess, nil
+			})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			cancel()
+			<-sub.Done()
+		})
+		return sub, cancel
+	}
+	first, stop := subscribe()
+	second, _ := subscribe()
+	if first.Name() != "orders" || second.Name() != "orders#2" {
+		t.Fatalf("expected orders and orders#2, got %s and %s", first.Name(), second.Name())
+	}
+	stop()
+	<-first.Done()
+	waitFor(t, "the removal", func() bool { return len(w.Subscriptions()) == 1 })
+	if third, _ := subscribe(); third.Name() != "orders" {
+		t.Fatalf("expected the freed name orders, got %s", third.Name())
+	}
+}
@@ -0,0 +1,143 @@
+package worker_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/flowable/flowable-external-client-golang/flowable"
+)
+
+// headerRecorder returns a server recording the given request header of every call.
+func headerRecorder(t *testing.T, header string) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Header.Get(header))
+		mu.Unlock()
+		io.WriteString(w, `{"data":[],"total":0}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), seen...)
+	}
+}
+
+func TestHeaderAuthenticator(t *testing.T) {
+	srv, seen := headerRecorder(t, "X-API-Key")
+	useAuthenticator(t, flowable.NewAPIKeyAuthenticator("X-API-Key", "k1"))
+	flowable.List_jobs(srv.URL)
+	if got := seen(); len(got) != 1 || got[0] != "k1" {
+		t.Fatalf("expected X-API-Key k1, got %v", got)
+	}
+}
+
+func TestEnvAuthenticator(t *testing.T) {
+	srv, seen := headerRecorder(t, "Authorization")
+	useAuthenticator(t, flowable.EnvAuthenticator{})
+	t.Setenv("FLOWABLE_TOKEN", "")
+	t.Setenv("FLOWABLE_USERNAME", "admin")
+	t.Setenv("FLOWABLE_PASSWORD", "test")
+	flowable.List_jobs(srv.URL)
+	t.Setenv("FLOWABLE_TOKEN", "tok")
+	flowable.List_jobs(srv.URL)
+	got := seen()
+	if len(got) != 2 || got[0] != "Basic YWRtaW46dGVzdA==" || got[1] != "Bearer tok" {
+		t.Fatalf("unexpected Authorization headers: %v", got)
+	}
+}
+
+func TestFileAuthenticator_PicksUpRotation(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte("first\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	srv, seen := headerRecorder(t, "Authorization")
+	auth := flowable.NewSecretDirAuthenticator(dir)
+	auth.CheckInterval = time.Nanosecond
+	useAuthenticator(t, auth)
+
+	flowable.List_jobs(srv.URL)
+	if err := os.WriteFile(tokenFile, []byte("second-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	flowable.List_jobs(srv.URL)
+	got := seen()
+	if len(got) != 2 || got[0] != "Bearer first" || got[1] != "Bearer second-token" {
+		t.Fatalf("expected rotated token, got %v", got)
+	}
+}
+
+func TestFileAuthenticator_MissingFile(t *testing.T) {
+	srv, seen := headerRecorder(t, "Authorization")
+	useAuthenticator(t, flowable.NewSecretDirAuthenticator(t.TempDir()))
+	if _, _, err := flowable.List_jobs(srv.URL); err == nil {
+		t.Fatalf("expected error for missing credential files")
+	}
+	if len(seen()) != 0 {
+		t.Fatalf("expected no request without credentials")
+	}
+}
+
+func TestSetAuth_ConcurrentRotation(t *testing.T) {
+	srv, _ := headerRecorder(t, "Authorization")
+	defer flowable.SetAuth("", "")
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				flowable.List_jobs(srv.URL)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				flowable.SetAuth("user", "pass")
+				flowable.SetBearerToken("")
+				flowable.SetDefaultHeader("X-Rotation", "1")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestFileAuthenticator_KeepsCredentialsDuringRotation(t *testing.T) {
+	quietLogs(t)
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte("first\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	srv, seen := headerRecorder(t, "Authorization")
+	auth := flowable.NewSecretDirAuthenticator(dir)
+	auth.CheckInterval = time.Nanosecond
+	useAuthenticator(t, auth)
+
+	flowable.List_jobs(srv.URL)
+	if err := os.WriteFile(tokenFile, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := flowable.List_jobs(srv.URL); err != nil {
+		t.Fatalf("expected the previous token while the file is empty, got %v", err)
+	}
+	if err := os.WriteFile(tokenFile, []byte("second\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	flowable.List_jobs(srv.URL)
+	got := seen()
+	if len(got) != 3 || got[0] != "Bearer first" || got[1] != "Bearer first" || got[2] != "Bearer second" {
+		t.Fatalf("unexpected Authorization headers: %v", got)
+	}
+}
@@ -0,0 +1,219 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/flowable/flowable-external-client-golang/flowable"
+)
+
+func recordBackPressure(t *testing.T) func() []flowable.BackPressureEvent {
+	t.Helper()
+	var mu sync.Mutex
+	var events []flowable.BackPressureEvent
+	flowable.SetBackPressureHook(func(ev flowable.BackPressureEvent) {
+		mu.Lock()
+		events = append(events, ev)
+		mu.Unlock()
+	})
+	t.Cleanup(func() { flowable.SetBackPressureHook(nil) })
+	return func() []flowable.BackPressureEvent {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]flowable.BackPressureEvent(nil), events...)
+	}
+}
+
+func TestAcquireJobs_BackPressureError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+	_, _, status, err := flowable.Acquire_jobs(flowable.AcquireRequest{Topic: "t", URL: srv.URL})
+	var bp *flowable.BackPressureError
+	if !errors.As(err, &bp) || bp.RetryAfter != 7*time.Second || status != 429 {
+		t.Fatalf("expected back-pressure error with 7s, got status=%d err=%v", status, err)
+	}
+}
+
+func TestSubscribe_HonorsRetryAfter(t *testing.T) {
+	flowable.SetEnableLogging(false)
+	defer flowable.SetEnableLogging(true)
+	events := recordBackPressure(t)
+
+	var mu sync.Mutex
+	var acquires []time.Time
+	var reports int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/external-job-api/acquire/jobs" {
+			mu.Lock()
+			acquires = append(acquires, time.Now())
+			n := len(acquires)
+			mu.Unlock()
+			switch n {
+			case 1:
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusServiceUnavailable)
+			case 2:
+				io.WriteString(w, `[{"id":"job-1"}]`)
+			default:
+				io.WriteString(w, `[]`)
+			}
+			return
+		}
+		if atomic.AddInt32(&reports, 1) == 1 {
+			w.Header().Set("Retry-After", time.Now().Add(2*time.Second).UTC().Format(http.TimeFormat))
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	var handled int32
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		flowable.SubscribeContext(ctx, flowable.AcquireRequest{Topic: "t", WorkerId: "w", URL: srv.URL, Interval: 10 * time.Millisecond},
+			func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+				atomic.AddInt32(&handled, 1)
+				return flowable.HandlerSuccess, nil
+			})
+	}()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for atomic.LoadInt32(&reports) < 2 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	cancel()
+	<-stopped
+
+	if atomic.LoadInt32(&reports) != 2 {
+		t.Fatalf("expected the report to be retried once, got %d calls", reports)
+	}
+	if atomic.LoadInt32(&handled) != 1 {
+		t.Fatalf("expected the handler to run only for the job, got %d calls", handled)
+	}
+	mu.Lock()
+	gap := acquires[1].Sub(acquires[0])
+	mu.Unlock()
+	if gap < 900*time.Millisecond {
+		t.Fatalf("expected the acquire loop to pause about 1s, paused %v", gap)
+	}
+	got := events()
+	if len(got) != 2 || got[0].Operation != "acquire" || got[0].Status != 503 || got[0].Pause != time.Second ||
+		got[1].Operation != "report" || got[1].Status != 429 || got[1].Pause <= 0 || got[1].Pause > 2*time.Second {
+		t.Fatalf("unexpected back-pressure events: %+v", got)
+	}
+}
+
+func TestReport_RetriesStopAtLockExpiry(t *testing.T) {
+	flowable.SetEnableLogging(false)
+	defer flowable.SetEnableLogging(true)
+	var once sync.Once
+	var reports int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/external-job-api/acquire/jobs" {
+			body := `[]`
+			once.Do(func() {
+				body = `[{"id":"job-1","lockExpirationTime":"` + time.Now().Add(500*time.Millisecond).UTC().Format(time.RFC3339Nano) + `"}]`
+			})
+			io.WriteString(w, body)
+			return
+		}
+		atomic.AddInt32(&reports, 1)
+		w.Header().Set("Retry-After", "600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		flowable.SubscribeContext(ctx, flowable.AcquireRequest{Topic: "t", WorkerId: "w", URL: srv.URL, Interval: 10 * time.Millisecond},
+			func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+				return flowable.HandlerSuccess, nil
+			})
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&reports) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("report kept retrying after the lock expired")
+	}
+	if n := atomic.LoadInt32(&reports); n != 1 {
+		t.Fatalf("expected a single report attempt, got %d", n)
+	}
+}
+
+func TestListJobs_BackPressureKeepsStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	status, _, err := flowable.List_jobs(srv.URL)
+	var bp *flowable.BackPressureError
+	if status != http.StatusServiceUnavailable || !errors.As(err, &bp) {
+		t.Fatalf("expected status 503 with a back-pressure error, got status=%d err=%v", status, err)
+	}
+}
+
+func TestSubscribe_BackPressureLoggedUnderAcquire(t *testing.T) {
+	sink := useJSONLogger(t)
+	events := recordBackPressure(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	flowable.SetLogLevel(flowable.ComponentAcquire, slog.LevelError)
+	defer flowable.SetLogLevel(flowable.ComponentAcquire, slog.LevelInfo)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		flowable.SubscribeContext(ctx, flowable.AcquireRequest{Topic: "t", WorkerId: "w", URL: srv.URL, Interval: 5 * time.Millisecond},
+			func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+				return flowable.HandlerSuccess, nil
+			})
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+	waitFor := func(what string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", what)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	waitFor("back-pressure", func() bool { return len(events()) >= 2 })
+	if rec := sink.find(t, "back-pressure from server, pausing"); rec != nil {
+		t.Fatalf("expected the acquire level to filter the record, got %v", rec)
+	}
+
+	flowable.SetLogLevel(flowable.ComponentAcquire, slog.LevelInfo)
+	waitFor("a record", func() bool { return sink.find(t, "back-pressure from server, pausing") != nil })
+	if rec := sink.find(t, "back-pressure from server, pausing"); rec["component"] != "acquire" || rec["operation"] != "acquire" {
+		t.Fatalf("unexpected record %v", rec)
+	}
+}
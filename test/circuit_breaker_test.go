@@ -0,0 +1,285 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/flowable/flowable-external-client-golang/flowable"
+)
+
+func installBreaker(t *testing.T, opts flowable.CircuitBreakerOptions) (*flowable.CircuitBreaker, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var transitions []string
+	hook := opts.OnStateChange
+	opts.OnStateChange = func(node string, from, to flowable.CircuitState) {
+		if hook != nil {
+			hook(node, from, to)
+		}
+		mu.Lock()
+		transitions = append(transitions, from.String()+"->"+to.String())
+		mu.Unlock()
+	}
+	cb := flowable.NewCircuitBreaker(opts)
+	flowable.SetCircuitBreaker(cb)
+	t.Cleanup(func() { flowable.SetCircuitBreaker(nil) })
+	return cb, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), transitions...)
+	}
+}
+
+func TestCircuitBreaker_OpensAndRecovers(t *testing.T) {
+	flowable.SetEnableLogging(false)
+	defer flowable.SetEnableLogging(true)
+	var healthy atomic.Bool
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		io.WriteString(w, `{"data":[]}`)
+	}))
+	defer srv.Close()
+	cb, transitions := installBreaker(t, flowable.CircuitBreakerOptions{FailureThreshold: 3, CoolDown: 100 * time.Millisecond})
+
+	for i := 0; i < 3; i++ {
+		flowable.List_jobs(srv.URL)
+	}
+	if cb.State() != flowable.CircuitOpen {
+		t.Fatalf("expected open circuit after 3 failures, got %s", cb.State())
+	}
+	if _, _, err := flowable.List_jobs(srv.URL); !errors.Is(err, flowable.ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if atomic.LoadInt32(&hits) != 3 {
+		t.Fatalf("expected no request while open, got %d", hits)
+	}
+
+	healthy.Store(true)
+	if err := cb.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if status, _, err := flowable.List_jobs(srv.URL); err != nil || status != 200 {
+		t.Fatalf("expected probe to succeed, got status=%d err=%v", status, err)
+	}
+	if cb.State() != flowable.CircuitClosed {
+		t.Fatalf("expected closed circuit after successful probe, got %s", cb.State())
+	}
+	got := transitions()
+	want := []string{"closed->open", "open->half-open", "half-open->closed"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Fatalf("expected transitions %v, got %v", want, got)
+	}
+}
+
+func TestCircuitBreaker_FailedProbeReopens(t *testing.T) {
+	flowable.SetEnableLogging(false)
+	defer flowable.SetEnableLogging(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	cb, _ := installBreaker(t, flowable.CircuitBreakerOptions{FailureThreshold: 1, CoolDown: 50 * time.Millisecond})
+
+	flowable.List_jobs(srv.URL)
+	time.Sleep(60 * time.Millisecond)
+	flowable.List_jobs(srv.URL)
+	if cb.State() != flowable.CircuitOpen {
+		t.Fatalf("expected failed probe to reopen the circuit, got %s", cb.State())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := cb.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected Wait to block during cool-down, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_SubscribePausesAndReportWaits(t *testing.T) {
+	flowable.SetEnableLogging(false)
+	defer flowable.SetEnableLogging(true)
+	var acquires, completes int32
+	var completedAt atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/external-job-api/acquire/jobs":
+			if atomic.AddInt32(&acquires, 1) == 1 {
+				io.WriteString(w, `[{"id":"job-1"}]`)
+				return
+			}
+			io.WriteString(w, `[]`)
+		case "/external-job-api/jobs":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			atomic.AddInt32(&completes, 1)
+			completedAt.Store(time.Now().UnixNano())
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+	cb, _ := installBreaker(t, flowable.CircuitBreakerOptions{FailureThreshold: 2, CoolDown: 300 * time.Millisecond})
+
+	var openedAt time.Time
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		flowable.SubscribeContext(ctx, flowable.AcquireRequest{Topic: "t", WorkerId: "w", URL: srv.URL, Interval: time.Millisecond},
+			func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+				// Simulate Flowable going down while the job is being handled.
+				flowable.List_jobs(srv.URL)
+				flowable.List_jobs(srv.URL)
+				openedAt = time.Now()
+				return flowable.HandlerSuccess, nil
+			})
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&completes) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-stopped
+
+	if atomic.LoadInt32(&completes) != 1 {
+		t.Fatalf("expected the report to be delivered once, got %d", completes)
+	}
+	if waited := time.Unix(0, completedAt.Load()).Sub(openedAt); waited < 250*time.Millisecond {
+		t.Fatalf("expected the report to wait for the cool-down, waited %v", waited)
+	}
+	if cb.State() != flowable.CircuitClosed {
+		t.Fatalf("expected circuit to close after the report, got %s", cb.State())
+	}
+}
+
+func TestCircuitBreaker_SubscribeWaitsWhileOpen(t *testing.T) {
+	flowable.SetEnableLogging(false)
+	defer flowable.SetEnableLogging(true)
+	var acquires int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/external-job-api/jobs" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		atomic.AddInt32(&acquires, 1)
+		io.WriteString(w, `[]`)
+	}))
+	defer srv.Close()
+	cb, _ := installBreaker(t, flowable.CircuitBreakerOptions{FailureThreshold: 1, CoolDown: 200 * time.Millisecond})
+	flowable.List_jobs(srv.URL)
+	if cb.State() != flowable.CircuitOpen {
+		t.Fatalf("expected open circuit, got %s", cb.State())
+	}
+
+	var handled int32
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		flowable.SubscribeContext(ctx, flowable.AcquireRequest{Topic: "t", WorkerId: "w", URL: srv.URL, Interval: time.Millisecond},
+			func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+				atomic.AddInt32(&handled, 1)
+				return flowable.HandlerSuccess, nil
+			})
+	}()
+	time.Sleep(100 * time.Millisecond)
+	if n := atomic.LoadInt32(&acquires); n != 0 {
+		t.Fatalf("expected no acquire while the circuit is open, got %d", n)
+	}
+	time.Sleep(250 * time.Millisecond)
+	cancel()
+	<-stopped
+	if n := atomic.LoadInt32(&acquires); n == 0 {
+		t.Fatalf("expected acquiring to resume after the cool-down")
+	}
+	if n := atomic.LoadInt32(&handled); n != 0 {
+		t.Fatalf("expected the handler not to be called for an open circuit, got %d calls", n)
+	}
+}
+
+// failingAuthenticator rejects every request before it is sent.
+type failingAuthenticator struct{}
+
+func (failingAuthenticator) Authenticate(*http.Request) error {
+	return errors.New("no credentials")
+}
+
+func TestCircuitBreaker_IgnoresLocalErrors(t *testing.T) {
+	flowable.SetEnableLogging(false)
+	defer flowable.SetEnableLogging(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"data":[]}`)
+	}))
+	defer srv.Close()
+	cb, _ := installBreaker(t, flowable.CircuitBreakerOptions{FailureThreshold: 2})
+	useAuthenticator(t, failingAuthenticator{})
+
+	for i := 0; i < 5; i++ {
+		if _, _, err := flowable.List_jobs(srv.URL); err == nil {
+			t.Fatalf("expected the authenticator error")
+		}
+	}
+	if cb.State() != flowable.CircuitClosed {
+		t.Fatalf("expected a closed circuit after local errors, got %s", cb.State())
+	}
+}
+
+func TestCircuitBreaker_CancelledProbeWakesWaiters(t *testing.T) {
+	flowable.SetEnableLogging(false)
+	defer flowable.SetEnableLogging(true)
+	var failing atomic.Bool
+	failing.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		io.Copy(io.Discard, r.Body)
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	coolDown := 300 * time.Millisecond
+	cb, _ := installBreaker(t, flowable.CircuitBreakerOptions{FailureThreshold: 1, CoolDown: coolDown})
+	flowable.List_jobs(srv.URL)
+	failing.Store(false)
+	time.Sleep(coolDown + 20*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	probed := make(chan struct{})
+	go func() {
+		defer close(probed)
+		flowable.SubscribeContext(ctx, flowable.AcquireRequest{Topic: "t", WorkerId: "w", URL: srv.URL, Interval: time.Hour},
+			func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+				return flowable.HandlerSuccess, nil
+			})
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for cb.State() != flowable.CircuitHalfOpen {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the probe")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	start := time.Now()
+	if err := cb.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited >= coolDown {
+		t.Fatalf("expected the released probe to wake the waiter, waited %s", waited)
+	}
+	<-probed
+}
@@ -0,0 +1,203 @@
+package worker_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flowable/flowable-external-client-golang/flowable"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfig_YAMLWithEnvOverrides(t *testing.T) {
+	path := writeConfig(t, "worker.yaml", `
+urls: [http://flowable-1:8090/, http://flowable-2:8090]
+workerId: w1
+auth:
+  username: admin
+  password: secret
+defaults:
+  lockDuration: PT10M
+  interval: 2s
+  retries: 3
+topics:
+  - topic: orders
+    concurrency: 4
+  - topic: order-events
+    scopeType: CMMN
+`)
+	t.Setenv("FLOWABLE_PASSWORD", "from-env")
+	t.Setenv("FLOWABLE_BATCH_SIZE", "5")
+	t.Setenv("FLOWABLE_TOPIC_ORDER_EVENTS_LOCK_DURATION", "PT1M")
+	t.Setenv("FLOWABLE_TOPICS", "orders,invoices")
+
+	cfg, err := flowable.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Auth.Password != "from-env" {
+		t.Fatalf("expected the password from the environment, got %q", cfg.Auth.Password)
+	}
+	reqs := cfg.Requests()
+	if len(reqs) != 3 {
+		t.Fatalf("expected 3 subscriptions, got %+v", reqs)
+	}
+	want := []flowable.AcquireRequest{
+		{Topic: "orders", LockDuration: "PT10M", NumberOfTasks: 5, NumberOfRetries: 3, WorkerId: "w1", Interval: 2 * time.Second, Concurrency: 4},
+		{Topic: "order-events", LockDuration: "PT1M", NumberOfTasks: 5, NumberOfRetries: 3, WorkerId: "w1", ScopeType: "cmmn", Interval: 2 * time.Second, Concurrency: 1},
+		{Topic: "invoices", LockDuration: "PT10M", NumberOfTasks: 5, NumberOfRetries: 3, WorkerId: "w1", Interval: 2 * time.Second, Concurrency: 1},
+	}
+	for i, w := range want {
+		w.URL = "http://flowable-1:8090/"
+		w.URLs = []string{"http://flowable-2:8090"}
+		got := reqs[i]
+		if got.Topic != w.Topic || got.LockDuration != w.LockDuration || got.NumberOfTasks != w.NumberOfTasks ||
+			got.NumberOfRetries != w.NumberOfRetries || got.WorkerId != w.WorkerId || got.ScopeType != w.ScopeType ||
+			got.Interval != w.Interval || got.Concurrency != w.Concurrency || got.URL != w.URL || strings.Join(got.URLs, ",") != strings.Join(w.URLs, ",") {
+			t.Errorf("subscription %d:\n got %+v\nwant %+v", i, got, w)
+		}
+	}
+}
+
+func TestConfig_JSONAndBuiltinDefaults(t *testing.T) {
+	path := writeConfig(t, "worker.json", `{"urls": ["http://localhost:8090"], "auth": {"token": "abc"}, "topics": [{"topic": "t"}]}`)
+	cfg, err := flowable.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := cfg.Requests()[0]
+	if req.LockDuration != flowable.DefaultLockDuration || req.NumberOfTasks != flowable.DefaultBatchSize ||
+		req.Concurrency != flowable.DefaultConcurrency || req.Interval != flowable.DefaultInterval || req.WorkerId == "" {
+		t.Fatalf("expected the built-in defaults, got %+v", req)
+	}
+}
+
+func TestConfig_ValidationListsAllProblems(t *testing.T) {
+	path := writeConfig(t, "worker.yaml", `
+urls: [localhost:8090]
+auth: {password: secret}
+tls: {certFile: client.pem}
+defaults: {concurrency: -1}
+topics:
+  - topic: a
+    lockDuration: 10m
+  - topic: a
+    scopeType: dmn
+  - {}
+`)
+	t.Setenv("FLOWABLE_RETRIES", "many")
+	_, err := flowable.LoadConfig(path)
+	var cerr *flowable.ConfigError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("expected a ConfigError, got %v", err)
+	}
+	for _, want := range []string{
+		"FLOWABLE_RETRIES: \"many\" is not a whole number",
+		"urls[0]: ",
+		"auth.username: required when a password is set",
+		"tls: certFile and keyFile must be set together",
+		"defaults.concurrency: must not be negative, got -1",
+		"topics[0].lockDuration: \"10m\" is not a positive ISO-8601 duration (e.g. PT10M)",
+		"topics[1].topic: \"a\" is listed more than once",
+		"topics[1].scopeType: \"dmn\" must be bpmn or cmmn",
+		"topics[2].topic: required",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in:\n%v", want, err)
+		}
+	}
+}
+
+func TestConfig_UnknownKeysAreRejected(t *testing.T) {
+	for name, content := range map[string]string{
+		"worker.yaml": "urls: [http://localhost:8090]\ntopics: [{topic: t, lockDuraton: PT1M}]\n",
+		"worker.json": `{"urls": ["http://localhost:8090"], "topic": "t"}`,
+	} {
+		if _, err := flowable.LoadConfig(writeConfig(t, name, content)); err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("%s: expected an error naming the file, got %v", name, err)
+		}
+	}
+}
+
+func TestConfig_StringMasksSecrets(t *testing.T) {
+	t.Setenv("FLOWABLE_URLS", "http://localhost:8090")
+	t.Setenv("FLOWABLE_USERNAME", "admin")
+	t.Setenv("FLOWABLE_PASSWORD", "hunter2")
+	t.Setenv("FLOWABLE_TOPICS", "t")
+	cfg, err := flowable.LoadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := useJSONLogger(t)
+	flowable.Logger().Info("configuration", "config", cfg)
+	for _, out := range []string{cfg.String(), mustJSON(t, sink.records(t))} {
+		if strings.Contains(out, "hunter2") || !strings.Contains(out, "****") || !strings.Contains(out, "admin") {
+			t.Errorf("expected masked secrets, got:\n%s", out)
+		}
+	}
+	if cfg.Auth.Password != "hunter2" {
+		t.Fatal("masking must not change the configuration")
+	}
+}
+
+func TestConfig_ExplicitZeroOverridesDefaults(t *testing.T) {
+	path := writeConfig(t, "worker.yaml", `
+urls: [http://localhost:8090]
+defaults: {retries: 5, batchSize: 3}
+topics:
+  - topic: once
+    retries: 0
+  - topic: inherited
+`)
+	cfg, err := flowable.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reqs := cfg.Requests()
+	if reqs[0].NumberOfRetries != 0 || reqs[0].NumberOfTasks != 3 {
+		t.Errorf("expected retries 0 to override the defaults, got %+v", reqs[0])
+	}
+	if reqs[1].NumberOfRetries != 5 || reqs[1].NumberOfTasks != 3 {
+		t.Errorf("expected the defaults to be inherited, got %+v", reqs[1])
+	}
+
+	path = writeConfig(t, "worker.yaml", "urls: [http://localhost:8090]\ntopics: [{topic: t, batchSize: 0, concurrency: 0}]\n")
+	_, err = flowable.LoadConfig(path)
+	for _, want := range []string{"topics[0].batchSize: must be at least 1, got 0", "topics[0].concurrency: must be at least 1, got 0"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q, got %v", want, err)
+		}
+	}
+}
+
+func TestConfig_ApplyRejectsCredentialsWithAuthenticator(t *testing.T) {
+	useAuthReset(t)
+	useAuthenticator(t, failingAuthenticator{})
+	cfg := &flowable.Config{URLs: []string{"http://localhost:8090"}, Auth: flowable.AuthConfig{Token: "abc"}}
+	if err := cfg.Apply(); err == nil || !strings.Contains(err.Error(), "SetAuthenticator") {
+		t.Fatalf("expected Apply to refuse credentials next to an Authenticator, got %v", err)
+	}
+	cfg.Auth = flowable.AuthConfig{}
+	if err := cfg.Apply(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// useAuthReset clears the credentials installed by the test.
+func useAuthReset(t *testing.T) {
+	t.Cleanup(func() {
+		flowable.SetAuth("", "")
+		flowable.SetBearerToken("")
+	})
+}
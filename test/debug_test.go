@@ -0,0 +1,136 @@
+package worker_test
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/flowable/flowable-external-client-golang/flowable"
+)
+
+// captureDebug enables debug tracing with opts and returns the traced lines.
+func captureDebug(t *testing.T, opts flowable.DebugOptions) func() []string {
+	t.Helper()
+	var mu sync.Mutex
+	var lines []string
+	opts.Logf = func(format string, args ...interface{}) {
+		mu.Lock()
+		lines = append(lines, fmt.Sprintf(format, args...))
+		mu.Unlock()
+	}
+	flowable.SetDebug(&opts)
+	t.Cleanup(func() { flowable.SetDebug(nil) })
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), lines...)
+	}
+}
+
+func TestDebug_TracesExchangeWithRedaction(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `[{"id":"job-1","variables":[{"name":"apiKey","type":"string","value":"s3cr3t"},{"name":"amount","type":"integer","value":5}]}]`)
+	}))
+	defer srv.Close()
+	useAuthenticator(t, flowable.BasicAuthenticator{Username: "admin", Password: "hunter2"})
+	lines := captureDebug(t, flowable.DebugOptions{SensitiveVariables: []string{"APIKEY"}})
+
+	if _, _, _, err := flowable.Acquire_jobs(flowable.AcquireRequest{Topic: "t", WorkerId: "w", URL: srv.URL}); err != nil {
+		t.Fatal(err)
+	}
+	got := lines()
+	if len(got) != 1 {
+		t.Fatalf("expected one trace, got %v", got)
+	}
+	trace := got[0]
+	for _, want := range []string{"http: POST " + srv.URL + "/external-job-api/acquire/jobs -> 200 in ", "Authorization: [REDACTED]", `"topic":"t"`, `"name":"apiKey","type":"string","value":"[REDACTED]"`, `"value":5`} {
+		if !strings.Contains(trace, want) {
+			t.Errorf("trace does not contain %q:\n%s", want, trace)
+		}
+	}
+	for _, secret := range []string{"s3cr3t", "hunter2", "YWRtaW46aHVudGVyMg=="} {
+		if strings.Contains(trace, secret) {
+			t.Errorf("trace leaks %q:\n%s", secret, trace)
+		}
+	}
+}
+
+func TestDebug_TruncatesBodiesAndRedactsHeaderAuth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, strings.Repeat("x", 100))
+	}))
+	defer srv.Close()
+	useAuthenticator(t, flowable.NewAPIKeyAuthenticator("X-Api-Key", "k-123"))
+	lines := captureDebug(t, flowable.DebugOptions{MaxBodyBytes: 10})
+
+	if _, _, err := flowable.List_jobs(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	trace := strings.Join(lines(), "\n")
+	if !strings.Contains(trace, "response body: xxxxxxxxxx...") || strings.Contains(trace, strings.Repeat("x", 11)) {
+		t.Errorf("expected the body to be truncated to 10 bytes:\n%s", trace)
+	}
+	if !strings.Contains(trace, "X-Api-Key: [REDACTED]") || strings.Contains(trace, "k-123") {
+		t.Errorf("expected the API key header to be redacted:\n%s", trace)
+	}
+}
+
+func TestDebug_TruncatesAtRuneBoundary(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "abcdefghi€€€")
+	}))
+	defer srv.Close()
+	lines := captureDebug(t, flowable.DebugOptions{MaxBodyBytes: 10})
+
+	if _, _, err := flowable.List_jobs(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	trace := strings.Join(lines(), "\n")
+	if !utf8.ValidString(trace) || !strings.Contains(trace, "response body: abcdefghi...") {
+		t.Errorf("expected the body to be cut before the euro sign:\n%s", trace)
+	}
+}
+
+func TestDebug_Disabled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `[]`)
+	}))
+	defer srv.Close()
+	lines := captureDebug(t, flowable.DebugOptions{})
+	flowable.SetDebug(nil)
+	if _, _, err := flowable.List_jobs(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if got := lines(); len(got) != 0 {
+		t.Fatalf("expected no trace output, got %v", got)
+	}
+}
+
+func TestDebug_StructuredTraceFollowsComponentLevel(t *testing.T) {
+	sink := useJSONLogger(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"data":[],"total":0}`)
+	}))
+	defer srv.Close()
+	flowable.SetDebug(&flowable.DebugOptions{})
+	t.Cleanup(func() { flowable.SetDebug(nil) })
+
+	flowable.List_jobs(srv.URL)
+	if rec := sink.find(t, "http exchange"); rec == nil || rec["component"] != "http" {
+		t.Fatalf("expected a structured trace of component http, got %v", rec)
+	}
+
+	before := len(sink.records(t))
+	flowable.SetLogLevel(flowable.ComponentHTTP, slog.LevelWarn)
+	defer flowable.SetLogLevel(flowable.ComponentHTTP, slog.LevelInfo)
+	flowable.List_jobs(srv.URL)
+	if got := sink.records(t); len(got) != before {
+		t.Fatalf("expected the trace to be filtered by the component level, got %v", got[before:])
+	}
+}
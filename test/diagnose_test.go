@@ -0,0 +1,224 @@
+package worker_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flowable/flowable-external-client-golang/flowable"
+)
+
+// flowableApp serves the external job API below contextPath and answers
+// requests without the expected basic auth user with authStatus.
+func flowableApp(t *testing.T, contextPath string, authStatus int, date func() time.Time) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if date != nil {
+			w.Header().Set("Date", date().UTC().Format(http.TimeFormat))
+		}
+		if r.URL.Path != contextPath+"/external-job-api/jobs" && r.URL.Path != contextPath+"/external-job-api/acquire/jobs" {
+			http.NotFound(w, r)
+			return
+		}
+		if user, _, _ := r.BasicAuth(); user != "admin" {
+			w.WriteHeader(authStatus)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case contextPath + "/external-job-api/jobs":
+			w.Write([]byte(`{"data":[],"total":0}`))
+		case contextPath + "/external-job-api/acquire/jobs":
+			w.Write([]byte(`[]`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// checkStatus returns the status and the hint of the check called name.
+func checkStatus(t *testing.T, d *flowable.Diagnosis, name string) (flowable.CheckStatus, string) {
+	t.Helper()
+	for _, c := range d.Checks {
+		if c.Name == name {
+			return c.Status, c.Hint
+		}
+	}
+	t.Fatalf("no %s check in %+v", name, d.Checks)
+	return "", ""
+}
+
+func TestDiagnose_AllChecksPass(t *testing.T) {
+	useAuthReset(t)
+	flowable.SetAuth("admin", "test")
+	srv := flowableApp(t, "/flowable-work", http.StatusUnauthorized, nil)
+	d := flowable.Diagnose(context.Background(), srv.URL+"/flowable-work")
+	if !d.OK {
+		t.Fatalf("expected all checks to pass:\n%s", d)
+	}
+	want := map[string]flowable.CheckStatus{
+		flowable.CheckURL: flowable.CheckPass, flowable.CheckDNS: flowable.CheckPass, flowable.CheckTCP: flowable.CheckPass,
+		flowable.CheckTLS: flowable.CheckSkip, flowable.CheckEndpoint: flowable.CheckPass, flowable.CheckAuth: flowable.CheckPass,
+		flowable.CheckClock: flowable.CheckPass, flowable.CheckAcquire: flowable.CheckPass,
+	}
+	for name, status := range want {
+		if got, _ := checkStatus(t, d, name); got != status {
+			t.Errorf("%s: got %s, want %s", name, got, status)
+		}
+	}
+	if !strings.HasSuffix(d.String(), "all checks passed\n") {
+		t.Fatalf("unexpected report:\n%s", d)
+	}
+}
+
+func TestDiagnose_MissingContextPath(t *testing.T) {
+	useAuthReset(t)
+	flowable.SetAuth("admin", "test")
+	srv := flowableApp(t, "/flowable-work", http.StatusUnauthorized, nil)
+	for base, hint := range map[string]string{
+		srv.URL: "use " + srv.URL + "/flowable-work as the base URL",
+		srv.URL + "/flowable-work/external-job-api": "remove it",
+	} {
+		d := flowable.Diagnose(context.Background(), base)
+		status, got := checkStatus(t, d, flowable.CheckEndpoint)
+		if d.OK || status != flowable.CheckFail || !strings.Contains(got, hint) {
+			t.Errorf("%s: expected the endpoint check to fail with %q, got %s %q", base, hint, status, got)
+		}
+		if status, _ := checkStatus(t, d, flowable.CheckAcquire); status != flowable.CheckSkip {
+			t.Errorf("%s: expected the acquire check to be skipped, got %s", base, status)
+		}
+	}
+}
+
+func TestDiagnose_AuthFailures(t *testing.T) {
+	for _, tc := range []struct {
+		status int
+		user   string
+		detail string
+		hint   string
+	}{
+		{http.StatusUnauthorized, "", "401", "configure credentials"},
+		{http.StatusUnauthorized, "guest", "401", "check the user name and password"},
+		{http.StatusForbidden, "guest", "403", "grant the user the privilege"},
+	} {
+		useAuthReset(t)
+		flowable.SetAuth(tc.user, "")
+		srv := flowableApp(t, "", tc.status, nil)
+		d := flowable.Diagnose(context.Background(), srv.URL)
+		status, hint := checkStatus(t, d, flowable.CheckAuth)
+		if status != flowable.CheckFail || !strings.Contains(hint, tc.hint) || !strings.Contains(d.String(), "FAIL  auth      "+tc.detail) {
+			t.Errorf("%d as %q: unexpected report:\n%s", tc.status, tc.user, d)
+		}
+		if status, _ := checkStatus(t, d, flowable.CheckEndpoint); status != flowable.CheckPass {
+			t.Errorf("%d: expected the endpoint to be found, got %s", tc.status, status)
+		}
+	}
+}
+
+func TestDiagnose_ClockSkew(t *testing.T) {
+	useAuthReset(t)
+	flowable.SetAuth("admin", "test")
+	for skew, want := range map[time.Duration]flowable.CheckStatus{
+		10 * time.Second: flowable.CheckWarn,
+		-3 * time.Minute: flowable.CheckFail,
+	} {
+		srv := flowableApp(t, "", http.StatusUnauthorized, func() time.Time { return time.Now().Add(skew) })
+		d := flowable.Diagnose(context.Background(), srv.URL)
+		if status, hint := checkStatus(t, d, flowable.CheckClock); status != want || !strings.Contains(hint, "NTP") {
+			t.Errorf("skew %s: got %s %q, want %s", skew, status, hint, want)
+		}
+	}
+}
+
+func TestDiagnose_TLS(t *testing.T) {
+	restoreHTTPClient(t)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(srv.Close)
+	flowable.SetHTTPClient(&http.Client{})
+	d := flowable.Diagnose(context.Background(), srv.URL)
+	if status, hint := checkStatus(t, d, flowable.CheckTLS); status != flowable.CheckFail || !strings.Contains(hint, "unknown CA") {
+		t.Fatalf("expected an unknown CA, got %s %q", status, hint)
+	}
+	flowable.SetHTTPClient(srv.Client())
+	d = flowable.Diagnose(context.Background(), srv.URL)
+	if status, _ := checkStatus(t, d, flowable.CheckTLS); status != flowable.CheckPass {
+		t.Fatalf("expected the handshake to pass with the test CA:\n%s", d)
+	}
+}
+
+func TestDiagnose_ThroughProxy(t *testing.T) {
+	restoreHTTPClient(t)
+	useAuthReset(t)
+	flowable.SetAuth("admin", "test")
+	// The fake application also acts as the forward proxy for plain http.
+	proxy := flowableApp(t, "/flowable-work", http.StatusUnauthorized, nil)
+	proxyURL, _ := url.Parse(proxy.URL)
+	proxyURL.User = url.UserPassword("proxy-user", "proxy-secret")
+	flowable.SetHTTPClient(&http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}})
+
+	d := flowable.Diagnose(context.Background(), "http://flowable.invalid:8090/flowable-work")
+	if !d.OK {
+		t.Fatalf("expected the HTTP checks to pass through the proxy:\n%s", d)
+	}
+	for _, c := range d.Checks {
+		switch c.Name {
+		case flowable.CheckDNS, flowable.CheckTCP, flowable.CheckTLS:
+			if c.Status != flowable.CheckSkip || !strings.Contains(c.Detail, proxyURL.Host) || strings.Contains(c.Detail, "proxy-secret") {
+				t.Errorf("%s: expected a skip naming the proxy, got %+v", c.Name, c)
+			}
+		default:
+			if c.Status != flowable.CheckPass {
+				t.Errorf("%s: expected a pass, got %+v", c.Name, c)
+			}
+		}
+	}
+}
+
+// wrappingTransport hides the *http.Transport it delegates to.
+type wrappingTransport struct{ next http.RoundTripper }
+
+func (t wrappingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return t.next.RoundTrip(r)
+}
+
+func TestDiagnose_WrappedTransportUsesEnvironmentProxy(t *testing.T) {
+	// http.ProxyFromEnvironment reads the environment once per process, so
+	// the checks run in a child process with HTTP_PROXY set.
+	if os.Getenv("DIAGNOSE_PROXY_CHILD") == "" {
+		proxy := flowableApp(t, "/flowable-work", http.StatusUnauthorized, nil)
+		cmd := exec.Command(os.Args[0], "-test.run=^TestDiagnose_WrappedTransportUsesEnvironmentProxy$", "-test.v")
+		cmd.Env = append(os.Environ(), "DIAGNOSE_PROXY_CHILD=1", "HTTP_PROXY="+proxy.URL, "NO_PROXY=")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("child: %v\n%s", err, out)
+		}
+		return
+	}
+	proxyURL, _ := url.Parse(os.Getenv("HTTP_PROXY"))
+	restoreHTTPClient(t)
+	useAuthReset(t)
+	flowable.SetAuth("admin", "test")
+	flowable.SetHTTPClient(&http.Client{Transport: wrappingTransport{&http.Transport{Proxy: http.ProxyFromEnvironment}}})
+
+	d := flowable.Diagnose(context.Background(), "http://flowable.invalid:8090/flowable-work")
+	if !d.OK {
+		t.Fatalf("expected the HTTP checks to pass through the proxy:\n%s", d)
+	}
+	if status, _ := checkStatus(t, d, flowable.CheckDNS); status != flowable.CheckSkip {
+		t.Fatalf("expected DNS to be skipped for the proxy %s:\n%s", proxyURL.Host, d)
+	}
+}
+
+func TestDiagnose_InvalidURL(t *testing.T) {
+	d := flowable.Diagnose(context.Background(), "localhost:8090")
+	if status, hint := checkStatus(t, d, flowable.CheckURL); d.OK || status != flowable.CheckFail || !strings.Contains(hint, "/flowable-work") {
+		t.Fatalf("unexpected report:\n%s", d)
+	}
+}
@@ -0,0 +1,240 @@
+package worker_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/flowable/flowable-external-client-golang/flowable"
+)
+
+func TestAcquireJobs_FailsOverToNextURL(t *testing.T) {
+	flowable.SetEnableLogging(false)
+	defer flowable.SetEnableLogging(true)
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	down.Close()
+	var broken int32
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&broken, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `[{"id":"job-1"}]`)
+	}))
+	defer up.Close()
+
+	req := flowable.AcquireRequest{Topic: "t", URL: down.URL, URLs: []string{failing.URL, up.URL}}
+	for i := 0; i < 3; i++ {
+		jobs, _, status, err := flowable.Acquire_jobs(req)
+		if err != nil || status != 200 || len(jobs) != 1 {
+			t.Fatalf("acquire %d: expected one job from the healthy node, got status=%d err=%v jobs=%v", i, status, err, jobs)
+		}
+	}
+	if n := atomic.LoadInt32(&broken); n != 1 {
+		t.Fatalf("expected the failing node to be skipped after its first failure, got %d calls", n)
+	}
+}
+
+func TestAcquireJobs_RoundRobin(t *testing.T) {
+	var counts [2]int32
+	var servers []*httptest.Server
+	for i := range counts {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&counts[i], 1)
+			io.WriteString(w, `[]`)
+		}))
+		defer srv.Close()
+		servers = append(servers, srv)
+	}
+	req := flowable.AcquireRequest{Topic: "t", URL: servers[0].URL, URLs: []string{servers[1].URL, servers[0].URL}}
+	for i := 0; i < 4; i++ {
+		if _, _, _, err := flowable.Acquire_jobs(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if counts[0] != 2 || counts[1] != 2 {
+		t.Fatalf("expected acquires to alternate between nodes, got %v", counts)
+	}
+}
+
+// failoverNodes starts two nodes. Only the first hands out a job, and it
+// answers the report with reportStatus; the second records the reports it receives.
+func failoverNodes(t *testing.T, reportStatus int) (first, second *httptest.Server, reports func() []string) {
+	t.Helper()
+	var once sync.Once
+	first = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/external-job-api/acquire/jobs" {
+			job := `[]`
+			once.Do(func() { job = `[{"id":"job-1"}]` })
+			io.WriteString(w, job)
+			return
+		}
+		w.WriteHeader(reportStatus)
+	}))
+	t.Cleanup(first.Close)
+	var mu sync.Mutex
+	var paths []string
+	second = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/external-job-api/acquire/jobs" {
+			io.WriteString(w, `[]`)
+			return
+		}
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(second.Close)
+	return first, second, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), paths...)
+	}
+}
+
+func runSubscription(t *testing.T, req flowable.AcquireRequest, handled chan<- struct{}) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		flowable.SubscribeContext(ctx, req, func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+			handled <- struct{}{}
+			return flowable.HandlerSuccess, nil
+		})
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+}
+
+func TestSubscribe_ReportFailsOverToOtherNode(t *testing.T) {
+	flowable.SetEnableLogging(false)
+	t.Cleanup(func() { flowable.SetEnableLogging(true) })
+	first, second, reports := failoverNodes(t, http.StatusBadGateway)
+
+	handled := make(chan struct{}, 1)
+	runSubscription(t, flowable.AcquireRequest{Topic: "t", WorkerId: "w", URL: first.URL, URLs: []string{second.URL}, Interval: 10 * time.Millisecond}, handled)
+	<-handled
+	deadline := time.Now().Add(5 * time.Second)
+	for len(reports()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := reports(); len(got) != 1 || got[0] != "/external-job-api/acquire/jobs/job-1/complete" {
+		t.Fatalf("expected the completion to be reported to the second node, got %v", got)
+	}
+}
+
+func TestSubscribe_ReportRejectionIsNotFailedOver(t *testing.T) {
+	flowable.SetEnableLogging(false)
+	t.Cleanup(func() { flowable.SetEnableLogging(true) })
+	first, second, reports := failoverNodes(t, http.StatusNotFound)
+
+	handled := make(chan struct{}, 1)
+	runSubscription(t, flowable.AcquireRequest{Topic: "t", WorkerId: "w", URL: first.URL, URLs: []string{second.URL}, Interval: 10 * time.Millisecond}, handled)
+	<-handled
+	time.Sleep(200 * time.Millisecond)
+	if got := reports(); len(got) != 0 {
+		t.Fatalf("expected a 404 not to be retried on another node, got %v", got)
+	}
+}
+
+func TestAcquireJobs_OpenCircuitFailsOverToNextURL(t *testing.T) {
+	flowable.SetEnableLogging(false)
+	defer flowable.SetEnableLogging(true)
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `[{"id":"job-1"}]`)
+	}))
+	defer up.Close()
+	var opened atomic.Value
+	cb, _ := installBreaker(t, flowable.CircuitBreakerOptions{FailureThreshold: 1, CoolDown: time.Minute,
+		OnStateChange: func(node string, from, to flowable.CircuitState) {
+			if to == flowable.CircuitOpen {
+				opened.Store(node)
+			}
+		}})
+
+	req := flowable.AcquireRequest{Topic: "t", URL: failing.URL, URLs: []string{up.URL}}
+	for i := 0; i < 3; i++ {
+		jobs, _, status, err := flowable.Acquire_jobs(req)
+		if err != nil || status != 200 || len(jobs) != 1 {
+			t.Fatalf("acquire %d: expected one job from the healthy node, got status=%d err=%v jobs=%v", i, status, err, jobs)
+		}
+	}
+	if cb.NodeState(failing.URL) != flowable.CircuitOpen || cb.NodeState(up.URL) != flowable.CircuitClosed {
+		t.Fatalf("expected only the failing node to be open, got %s and %s", cb.NodeState(failing.URL), cb.NodeState(up.URL))
+	}
+	if cb.State() != flowable.CircuitClosed {
+		t.Fatalf("expected the breaker to stay closed while a node is healthy, got %s", cb.State())
+	}
+	if got := opened.Load(); got != failing.URL {
+		t.Fatalf("expected the hook to name the failing node %s, got %v", failing.URL, got)
+	}
+}
+
+func TestSubscribe_NodeHealthIsDroppedWithTheSubscription(t *testing.T) {
+	flowable.SetEnableLogging(false)
+	t.Cleanup(func() { flowable.SetEnableLogging(true) })
+	var firstHits int32
+	first := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&firstHits, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		io.WriteString(w, `[]`)
+	}))
+	defer first.Close()
+	var secondHits int32
+	second := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&secondHits, 1)
+		io.WriteString(w, `[]`)
+	}))
+	defer second.Close()
+	req := flowable.AcquireRequest{Topic: "pruned", WorkerId: "w", URL: first.URL, URLs: []string{second.URL}, Interval: time.Hour}
+	subscribe := func() (stop func()) {
+		ctx, cancel := context.WithCancel(context.Background())
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			flowable.SubscribeContext(ctx, req, func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+				return flowable.HandlerSuccess, nil
+			})
+		}()
+		return func() {
+			cancel()
+			<-stopped
+		}
+	}
+
+	waitFor := func(what string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", what)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	// The first node fails once and is marked down; the acquire moves on.
+	stop := subscribe()
+	waitFor("the failover", func() bool { return atomic.LoadInt32(&secondHits) == 1 })
+	stop()
+
+	// A new subscription to the same nodes starts without that state.
+	stop = subscribe()
+	defer stop()
+	waitFor("an acquire on the first node", func() bool { return atomic.LoadInt32(&firstHits) == 2 })
+}
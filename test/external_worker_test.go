@@ -1,6 +1,7 @@
 package worker_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/flowable/flowable-external-client-golang/flowable"
@@ -8,7 +9,7 @@ import (
 )
 
 func TestExternalWorkerSuccess(t *testing.T) {
-	status, res := worker.ExternalWorker(200, `{"foo":"bar"}`)
+	status, res := worker.ExternalWorker(context.Background(), 200, `{"foo":"bar"}`)
 	if status != flowable.HandlerSuccess {
 		t.Fatalf("expected success status, got %v", status)
 	}
@@ -21,8 +22,18 @@ func TestExternalWorkerSuccess(t *testing.T) {
 }
 
 func TestExternalWorkerFailOnBadStatus(t *testing.T) {
-	status, _ := worker.ExternalWorker(500, "")
+	status, _ := worker.ExternalWorker(context.Background(), 500, "")
 	if status != flowable.HandlerFail {
 		t.Fatalf("expected fail status, got %v", status)
 	}
 }
+
+func TestExternalWorkerEchoesInputVar(t *testing.T) {
+	status, res := worker.ExternalWorker(context.Background(), 200, `{"variables":[{"name":"inputVar","type":"string","value":"in"}]}`)
+	if status != flowable.HandlerSuccess {
+		t.Fatalf("expected success status, got %v", status)
+	}
+	if flowable.GetVar(res.Variables, "inputVar") != "in" || flowable.GetVar(res.Variables, "dummy") != "a simple string" {
+		t.Fatalf("unexpected variables: %#v", res.Variables)
+	}
+}
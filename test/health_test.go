@@ -0,0 +1,306 @@
+package worker_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/flowable/flowable-external-client-golang/flowable"
+)
+
+// blockingJobServer hands out job-1 once and records reports.
+func blockingJobServer(t *testing.T, acquireStatus int) (*httptest.Server, <-chan string) {
+	t.Helper()
+	var once sync.Once
+	reports := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/external-job-api/acquire/jobs" {
+			if acquireStatus != http.StatusOK {
+				w.WriteHeader(acquireStatus)
+				return
+			}
+			body := `[]`
+			once.Do(func() { body = `[{"id":"job-1"}]` })
+			io.WriteString(w, body)
+			return
+		}
+		reports <- r.URL.Path
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, reports
+}
+
+func getHealth(t *testing.T, h http.Handler, path string) (int, flowable.HealthReport) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+	var r flowable.HealthReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &r); err != nil {
+		t.Fatalf("%s: invalid body %q: %v", path, rec.Body.String(), err)
+	}
+	return rec.Code, r
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func quietLogs(t *testing.T) {
+	flowable.SetEnableLogging(false)
+	t.Cleanup(func() { flowable.SetEnableLogging(true) })
+}
+
+func TestHealth_ReadyAfterSuccessfulAcquire(t *testing.T) {
+	quietLogs(t)
+	srv, _ := blockingJobServer(t, http.StatusOK)
+	w := flowable.NewWorker()
+	h := w.AdminHandler()
+
+	if code, r := getHealth(t, h, "/readyz"); code != 503 || !strings.Contains(strings.Join(r.Problems, ","), "no subscriptions") {
+		t.Fatalf("expected not ready without subscriptions, got %d %+v", code, r)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	sub, err := w.Subscribe(ctx, flowable.AcquireRequest{Topic: "ready", WorkerId: "w", URL: srv.URL, Interval: 5 * time.Millisecond},
+		func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+			return flowable.HandlerSuccess, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		<-sub.Done()
+	})
+	waitFor(t, "readiness", func() bool { return w.Readiness().OK })
+
+	code, r := getHealth(t, h, "/readyz")
+	if code != 200 || len(r.Subscriptions) != 1 || r.Subscriptions[0].Name != "ready" || r.Subscriptions[0].LastAcquireStatus != 200 {
+		t.Fatalf("unexpected readiness %d %+v", code, r)
+	}
+	if code, r := getHealth(t, h, "/healthz"); code != 200 || !r.OK {
+		t.Fatalf("unexpected health %d %+v", code, r)
+	}
+}
+
+func TestHealth_NotReadyWhenAuthFails(t *testing.T) {
+	quietLogs(t)
+	srv, _ := blockingJobServer(t, http.StatusUnauthorized)
+	w := flowable.NewWorker()
+	ctx, cancel := context.WithCancel(context.Background())
+	sub, err := w.Subscribe(ctx, flowable.AcquireRequest{Topic: "auth", WorkerId: "w", URL: srv.URL, Interval: 5 * time.Millisecond},
+		func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+			return flowable.HandlerFail, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		<-sub.Done()
+	})
+	waitFor(t, "an acquire", func() bool { return !sub.Status().LastAcquire.IsZero() })
+	r := w.Readiness()
+	if r.OK || !strings.Contains(strings.Join(r.Problems, ","), "auth: authentication failed (status 401)") {
+		t.Fatalf("expected an authentication problem, got %+v", r)
+	}
+}
+
+func TestHealth_ReadyUntilAcquiresFailInARow(t *testing.T) {
+	quietLogs(t)
+	srv, _ := blockingJobServer(t, http.StatusTooManyRequests)
+	w := flowable.NewWorker()
+	w.ReadinessFailures = 2
+	ctx, cancel := context.WithCancel(context.Background())
+	sub, err := w.Subscribe(ctx, flowable.AcquireRequest{Topic: "busy", WorkerId: "w", URL: srv.URL, Interval: 200 * time.Millisecond},
+		func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+			return flowable.HandlerSuccess, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		<-sub.Done()
+	})
+	waitFor(t, "an acquire", func() bool { return sub.Status().AcquireFailures == 1 })
+	if r := w.Readiness(); !r.OK {
+		t.Fatalf("expected one failed acquire to keep the worker ready, got %+v", r)
+	}
+	waitFor(t, "a second acquire", func() bool { return sub.Status().AcquireFailures >= 2 })
+	if r := w.Readiness(); r.OK || !strings.Contains(strings.Join(r.Problems, ","), "busy: 2 acquires in a row failed") {
+		t.Fatalf("expected not ready after two failed acquires, got %+v", r)
+	}
+}
+
+func TestHealth_NotReadyAfterAFailedAcquireByDefault(t *testing.T) {
+	quietLogs(t)
+	srv, _ := blockingJobServer(t, http.StatusTooManyRequests)
+	w := flowable.NewWorker()
+	ctx, cancel := context.WithCancel(context.Background())
+	sub, err := w.Subscribe(ctx, flowable.AcquireRequest{Topic: "busy", WorkerId: "w", URL: srv.URL, Interval: time.Hour},
+		func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+			return flowable.HandlerSuccess, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		<-sub.Done()
+	})
+	waitFor(t, "an acquire", func() bool { return sub.Status().AcquireFailures == 1 })
+	if r := w.Readiness(); r.OK || !strings.Contains(strings.Join(r.Problems, ","), "busy: 1 acquires in a row failed") {
+		t.Fatalf("expected not ready after the last acquire failed, got %+v", r)
+	}
+}
+
+func TestHealth_LongJobWithinLockIsHealthy(t *testing.T) {
+	quietLogs(t)
+	srv, _ := blockingJobServer(t, http.StatusOK)
+	w := flowable.NewWorker()
+	w.StallTimeout = 10 * time.Millisecond
+	started := make(chan struct{})
+	release := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	sub, err := w.Subscribe(ctx, flowable.AcquireRequest{Topic: "long", WorkerId: "w", URL: srv.URL, Interval: 5 * time.Millisecond, LockDuration: "PT1M"},
+		func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+			close(started)
+			<-release
+			return flowable.HandlerSuccess, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		close(release)
+		cancel()
+		<-sub.Done()
+	})
+	<-started
+	time.Sleep(50 * time.Millisecond)
+	if r := w.Health(); !r.OK {
+		t.Fatalf("expected a job within its lock to be healthy, got %+v", r)
+	}
+}
+
+func TestHealth_JobPastLockExpiryIsNotReady(t *testing.T) {
+	quietLogs(t)
+	srv, _ := blockingJobServer(t, http.StatusOK)
+	w := flowable.NewWorker()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	sub, err := w.Subscribe(ctx, flowable.AcquireRequest{Topic: "stuck", WorkerId: "w", URL: srv.URL, Interval: 5 * time.Millisecond, LockDuration: "PT0.02S"},
+		func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+			close(started)
+			<-release
+			return flowable.HandlerSuccess, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		close(release)
+		cancel()
+		<-sub.Done()
+	})
+	<-started
+	waitFor(t, "the expired lock to be detected", func() bool { return !w.Readiness().OK })
+	if r := w.Readiness(); !strings.Contains(strings.Join(r.Problems, ","), "stuck: job job-1") {
+		t.Fatalf("expected the stuck job to be named, got %+v", r)
+	}
+	if r := w.Health(); !r.OK {
+		t.Fatalf("expected a job past its lock to keep the worker healthy, got %+v", r)
+	}
+}
+
+func TestHealth_DrainFinishesInFlightJobs(t *testing.T) {
+	quietLogs(t)
+	srv, reports := blockingJobServer(t, http.StatusOK)
+	w := flowable.NewWorker()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	_, err := w.Subscribe(context.Background(), flowable.AcquireRequest{Topic: "drain", WorkerId: "w", URL: srv.URL, Interval: 5 * time.Millisecond},
+		func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+			close(started)
+			<-release
+			return flowable.HandlerSuccess, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	drained := make(chan error, 1)
+	go func() { drained <- w.Drain(context.Background()) }()
+	waitFor(t, "draining", w.Draining)
+	if r := w.Readiness(); r.OK || !strings.Contains(strings.Join(r.Problems, ","), "draining") {
+		t.Fatalf("expected not ready while draining, got %+v", r)
+	}
+	if _, err := w.Subscribe(context.Background(), flowable.AcquireRequest{Topic: "late", URL: srv.URL}, nil); !errors.Is(err, flowable.ErrDraining) {
+		t.Fatalf("expected ErrDraining, got %v", err)
+	}
+	select {
+	case err := <-drained:
+		t.Fatalf("drain returned before the job finished: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	if err := <-drained; err != nil {
+		t.Fatal(err)
+	}
+	if got := <-reports; got != "/external-job-api/acquire/jobs/job-1/complete" {
+		t.Fatalf("unexpected report %s", got)
+	}
+	if subs := w.Subscriptions(); len(subs) != 0 {
+		t.Fatalf("expected no subscriptions after drain, got %d", len(subs))
+	}
+}
+
+func TestHealth_DrainDeadlineCancelsHandlers(t *testing.T) {
+	quietLogs(t)
+	srv, reports := blockingJobServer(t, http.StatusOK)
+	w := flowable.NewWorker()
+	started := make(chan struct{})
+	sub, err := w.Subscribe(context.Background(), flowable.AcquireRequest{Topic: "deadline", WorkerId: "w", URL: srv.URL, Interval: 5 * time.Millisecond},
+		func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+			close(started)
+			<-ctx.Done()
+			return flowable.HandlerFail, &flowable.HandlerResult{ErrorMessage: ctx.Err().Error()}
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := w.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the drain deadline to pass, got %v", err)
+	}
+	<-sub.Done()
+	if got := <-reports; got != "/external-job-api/acquire/jobs/job-1/fail" {
+		t.Fatalf("expected the cancelled job to be reported as failed, got %s", got)
+	}
+}
+
+func TestHealth_InvalidURLIsRejected(t *testing.T) {
+	w := flowable.NewWorker()
+	if _, err := w.Subscribe(context.Background(), flowable.AcquireRequest{Topic: "t", URL: "localhost:8090"}, nil); !errors.Is(err, flowable.ErrInvalidBaseURL) {
+		t.Fatalf("expected ErrInvalidBaseURL, got %v", err)
+	}
+}
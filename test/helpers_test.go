@@ -0,0 +1,16 @@
+package worker_test
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// mustJSON marshals v or fails the test.
+func mustJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
@@ -263,7 +263,10 @@ func TestFailJob(t *testing.T) {
 	job := acquireJobForInstance(t, "myTopic", "bpmn", processInstanceID)
 	jobID := job["id"].(string)
 
-	initialRetries := job["retries"].(float64)
+	initialRetries, err := job["retries"].(json.Number).Float64()
+	if err != nil {
+		t.Fatalf("retries: %v", err)
+	}
 	failJob(t, jobID)
 
 	// Verify retries decremented
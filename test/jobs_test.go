@@ -0,0 +1,192 @@
+package worker_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/flowable/flowable-external-client-golang/flowable"
+)
+
+// jobsAPI fakes the job endpoints and records the requests it receives.
+type jobsAPI struct {
+	mu       sync.Mutex
+	requests []string // method path body
+}
+
+func (a *jobsAPI) last() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.requests) == 0 {
+		return ""
+	}
+	return a.requests[len(a.requests)-1]
+}
+
+func newJobsAPI(t *testing.T) (*httptest.Server, *jobsAPI) {
+	t.Helper()
+	a := &jobsAPI{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		a.mu.Lock()
+		a.requests = append(a.requests, strings.TrimSpace(r.Method+" "+r.URL.RequestURI()+" "+string(body)))
+		a.mu.Unlock()
+		switch r.URL.Path {
+		case "/external-job-api/jobs":
+			io.WriteString(w, `{"data":[{"id":"job-1","processInstanceId":"pi-1","elementId":"task","retries":3,"exceptionMessage":"boom"}],"total":1,"start":0,"size":1}`)
+		case "/external-job-api/jobs/job-1":
+			io.WriteString(w, `{"id":"job-1","processInstanceId":"pi-1","retries":3}`)
+		case "/external-job-api/jobs/job-1/exception-stacktrace":
+			w.Header().Set("Content-Type", "text/plain")
+			io.WriteString(w, "java.lang.RuntimeException: boom\n\tat Task.run")
+		case "/external-job-api/acquire/jobs":
+			io.WriteString(w, `[{"id":"job-2","scopeId":"case-1","scopeType":"cmmn","variables":[]}]`)
+		case "/external-job-api/jobs/missing":
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"message":"Not found","exception":"Could not find job with id 'missing'"}`)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, a
+}
+
+func TestJobs_GetAndStacktrace(t *testing.T) {
+	srv, api := newJobsAPI(t)
+	status, body, err := flowable.Get_job(srv.URL, "job-1")
+	if err != nil || status != 200 || !strings.Contains(body, `"pi-1"`) {
+		t.Fatalf("Get_job: %d %q %v", status, body, err)
+	}
+	status, body, err = flowable.Get_job_stacktrace(srv.URL+"/", "job-1")
+	if err != nil || status != 200 || !strings.HasPrefix(body, "java.lang.RuntimeException: boom") {
+		t.Fatalf("Get_job_stacktrace: %d %q %v", status, body, err)
+	}
+	if _, _, err := flowable.Get_job(srv.URL, "a/b"); err != nil {
+		t.Fatal(err)
+	}
+	if got := api.last(); got != "GET /external-job-api/jobs/a%2Fb" {
+		t.Fatalf("expected the job id to be escaped, got %q", got)
+	}
+}
+
+func TestJobs_ReportJob(t *testing.T) {
+	srv, api := newJobsAPI(t)
+	for resStatus, action := range map[flowable.HandlerStatus]string{
+		flowable.HandlerSuccess:       "complete",
+		flowable.HandlerFail:          "fail",
+		flowable.HandlerBPMNError:     "bpmnError",
+		flowable.HandlerCMMNTerminate: "cmmnTerminate",
+	} {
+		res := &flowable.HandlerResult{WorkerId: "w", Variables: []flowable.HandlerVariable{{Name: "a", Type: "long", Value: 1}}}
+		status, _, err := flowable.Report_job(srv.URL, "job-1", resStatus, res)
+		if err != nil || status != http.StatusNoContent {
+			t.Fatalf("%s: %d %v", resStatus, status, err)
+		}
+		method, rest, _ := strings.Cut(api.last(), " ")
+		path, body, _ := strings.Cut(rest, " ")
+		if method != "POST" || path != "/external-job-api/acquire/jobs/job-1/"+action {
+			t.Fatalf("%s: unexpected request %s %s", resStatus, method, path)
+		}
+		var sent flowable.HandlerResult
+		if err := json.Unmarshal([]byte(body), &sent); err != nil || sent.WorkerId != "w" || len(sent.Variables) != 1 || sent.Status != resStatus {
+			t.Fatalf("%s: unexpected body %s", resStatus, body)
+		}
+		// The same default error codes as the reports of a subscription.
+		if want := map[flowable.HandlerStatus]string{flowable.HandlerFail: "failed", flowable.HandlerBPMNError: "bpmnError", flowable.HandlerCMMNTerminate: "cmmnTerminate"}[resStatus]; sent.ErrorCode != want {
+			t.Fatalf("%s: expected error code %q, got %q", resStatus, want, sent.ErrorCode)
+		}
+	}
+	if _, _, err := flowable.Report_job(srv.URL, "job-1", "retry", nil); err == nil {
+		t.Fatal("expected an unknown handler status to be rejected")
+	}
+}
+
+// buildCLI builds cmd/flowable-jobs into a temporary directory.
+func buildCLI(t *testing.T) string {
+	t.Helper()
+	bin := filepath.Join(t.TempDir(), "flowable-jobs")
+	out, err := exec.Command("go", "build", "-o", bin, "github.com/flowable/flowable-external-client-golang/cmd/flowable-jobs").CombinedOutput()
+	if err != nil {
+		t.Fatalf("go build: %v\n%s", err, out)
+	}
+	return bin
+}
+
+func TestJobs_CLI(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the CLI")
+	}
+	srv, api := newJobsAPI(t)
+	bin := buildCLI(t)
+	varsFile := filepath.Join(t.TempDir(), "vars.json")
+	os.WriteFile(varsFile, []byte(`[{"name":"total","type":"double","value":12.5}]`), 0o600)
+
+	cli := func(args ...string) (string, string, int) {
+		t.Helper()
+		cmd := exec.Command(bin, args...)
+		cmd.Env = append(os.Environ(), "FLOWABLE_URLS="+srv.URL, "FLOWABLE_USERNAME=admin", "FLOWABLE_PASSWORD=test")
+		var stdout, stderr strings.Builder
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		err := cmd.Run()
+		code := 0
+		if exit, ok := err.(*exec.ExitError); ok {
+			code = exit.ExitCode()
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		return stdout.String(), stderr.String(), code
+	}
+
+	for _, tc := range []struct {
+		args    []string
+		code    int
+		stdout  []string
+		request string
+		stderr  string
+	}{
+		{args: []string{"list", "-process-instance-id", "pi-1", "-with-exception"}, stdout: []string{"ID", "LOCK OWNER", "job-1", "pi-1", "boom", "1 of 1 jobs"},
+			request: "GET /external-job-api/jobs?processInstanceId=pi-1&withException=true"},
+		{args: []string{"list", "-o", "json"}, stdout: []string{`"total": 1`}},
+		{args: []string{"get", "job-1", "-o", "json"}, stdout: []string{`"id": "job-1"`}, request: "GET /external-job-api/jobs/job-1"},
+		{args: []string{"get", "job-1"}, stdout: []string{"processInstanceId  pi-1", "retries            3"}},
+		{args: []string{"stacktrace", "job-1"}, stdout: []string{"java.lang.RuntimeException: boom"}},
+		{args: []string{"acquire", "-topic", "orders", "-n", "2", "-worker-id", "me"}, stdout: []string{"job-2", "cmmn", "case-1", "1 jobs acquired"},
+			request: `POST /external-job-api/acquire/jobs {"topic":"orders","lockDuration":"PT5M","numberOfTasks":2,"numberOfRetries":5,"workerId":"me","scopeType":""}`},
+		{args: []string{"complete", "job-1", "-worker-id", "me", "-var", "approved=true", "-var", "id:string=0042", "-vars", varsFile},
+			stdout:  []string{"job job-1: success reported (HTTP 204)"},
+			request: `POST /external-job-api/acquire/jobs/job-1/complete {"status":"success","workerId":"me","variables":[{"name":"approved","type":"boolean","value":true},{"name":"id","type":"string","value":"0042"},{"name":"total","type":"double","value":12.5}]}`},
+		{args: []string{"fail", "job-1", "-worker-id", "me", "-message", "bad input", "-retries", "0"},
+			request: `POST /external-job-api/acquire/jobs/job-1/fail {"status":"fail","workerId":"me","variables":null,"errorCode":"failed","errorMessage":"bad input","retries":0}`},
+		{args: []string{"bpmn-error", "job-1", "-worker-id", "me", "-code", "E42", "-o", "json"}, stdout: []string{`"outcome": "bpmnError"`},
+			request: `POST /external-job-api/acquire/jobs/job-1/bpmnError {"status":"bpmnError","workerId":"me","variables":null,"errorCode":"E42"}`},
+		{args: []string{"get", "missing"}, code: 1, stderr: "HTTP 404 Not Found: Not found: Could not find job with id 'missing'"},
+		{args: []string{"acquire"}, code: 2, stderr: "-topic is required"},
+		{args: []string{"complete", "job-1", "-var", "amount:long=many"}, code: 2, stderr: `variable amount: "many" is not a number`},
+		{args: []string{"nope"}, code: 2, stderr: `unknown command "nope"`},
+	} {
+		stdout, stderr, code := cli(tc.args...)
+		if code != tc.code {
+			t.Errorf("%v: exit code %d, want %d\n%s%s", tc.args, code, tc.code, stdout, stderr)
+			continue
+		}
+		for _, want := range tc.stdout {
+			if !strings.Contains(stdout, want) {
+				t.Errorf("%v: expected %q in output:\n%s", tc.args, want, stdout)
+			}
+		}
+		if tc.stderr != "" && !strings.Contains(stderr, tc.stderr) {
+			t.Errorf("%v: expected %q in errors:\n%s", tc.args, tc.stderr, stderr)
+		}
+		if tc.request != "" && api.last() != tc.request {
+			t.Errorf("%v: unexpected request\n got %s\nwant %s", tc.args, api.last(), tc.request)
+		}
+	}
+}
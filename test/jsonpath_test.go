@@ -0,0 +1,125 @@
+package worker_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/flowable/flowable-external-client-golang/flowable"
+)
+
+func orderVars(t *testing.T) []flowable.HandlerVariable {
+	t.Helper()
+	body := `{"variables":[{"name":"order","type":"json","value":{"id":42,"items":[{"sku":"A-1","qty":2},{"sku":"B-2","qty":1}]}}]}`
+	vars, err := flowable.ExtractVariablesFromBody(body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return vars
+}
+
+func TestLookup_FieldAndIndex(t *testing.T) {
+	vars := orderVars(t)
+	got, err := flowable.Lookup(vars, "order.items[0].sku")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "A-1" {
+		t.Fatalf("expected A-1, got %#v", got)
+	}
+	qty, err := flowable.Lookup(vars, "$.order.items[1].qty")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if qty != float64(1) {
+		t.Fatalf("expected numeric 1, got %#v", qty)
+	}
+}
+
+func TestLookup_Wildcard(t *testing.T) {
+	vars := orderVars(t)
+	got, err := flowable.Lookup(vars, "order.items[*].sku")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, []interface{}{"A-1", "B-2"}) {
+		t.Fatalf("expected [A-1 B-2], got %#v", got)
+	}
+}
+
+func TestLookup_JSONString(t *testing.T) {
+	vars := []flowable.HandlerVariable{{Name: "doc", Type: "json", Value: `{"a":{"b":["x","y"]}}`}}
+	got, err := flowable.Lookup(vars, "doc.a.b[1]")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "y" {
+		t.Fatalf("expected y, got %#v", got)
+	}
+}
+
+func TestLookup_NotFound(t *testing.T) {
+	vars := orderVars(t)
+	for _, path := range []string{"missing", "order.nope", "order.items[5].sku", "order.id.x"} {
+		if _, err := flowable.Lookup(vars, path); !errors.Is(err, flowable.ErrPathNotFound) {
+			t.Fatalf("expected ErrPathNotFound for %s, got %v", path, err)
+		}
+	}
+	if _, err := flowable.Lookup(vars, "order.items[x]"); err == nil || errors.Is(err, flowable.ErrPathNotFound) {
+		t.Fatalf("expected syntax error, got %v", err)
+	}
+}
+
+func TestSetPath_BuildsNestedVariable(t *testing.T) {
+	var vars []flowable.HandlerVariable
+	var err error
+	vars, err = flowable.SetPath(vars, "result.items[1].sku", "B-2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	vars, err = flowable.SetPath(vars, "result.total", 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	vars, err = flowable.SetPath(vars, "count", int64(7))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(vars) != 2 || vars[0].Type != "json" || vars[1].Type != "long" {
+		t.Fatalf("unexpected variables: %#v", vars)
+	}
+	b, _ := json.Marshal(vars[0].Value)
+	if string(b) != `{"items":[null,{"sku":"B-2"}],"total":3}` {
+		t.Fatalf("unexpected document: %s", b)
+	}
+	if _, err := flowable.SetPath(vars, "result.items[*].sku", "x"); err == nil {
+		t.Fatalf("expected error for wildcard in SetPath")
+	}
+}
+
+func TestSetPath_RejectsHugeIndex(t *testing.T) {
+	vars, err := flowable.SetPath(nil, "result.items[0]", "A-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := flowable.SetPath(vars, "result.items[1000000000]", "x"); err == nil {
+		t.Fatalf("expected error for an index far beyond the array")
+	}
+	b, _ := json.Marshal(vars[0].Value)
+	if string(b) != `{"items":["A-1"]}` {
+		t.Fatalf("unexpected document: %s", b)
+	}
+}
+
+func TestSetPath_NestedPathMakesVariableJSON(t *testing.T) {
+	vars := []flowable.HandlerVariable{{Name: "payload", Type: "string", Value: nil}}
+	vars, err := flowable.SetPath(vars, "payload.status", "done")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, _ := json.Marshal(vars[0].Value)
+	if vars[0].Type != "json" || string(b) != `{"status":"done"}` {
+		t.Fatalf("expected a json variable, got %s %s", vars[0].Type, b)
+	}
+}
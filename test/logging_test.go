@@ -0,0 +1,191 @@
+package worker_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/flowable/flowable-external-client-golang/flowable"
+)
+
+// logSink collects JSON log records.
+type logSink struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (s *logSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.Write(p)
+}
+
+func (s *logSink) records(t *testing.T) []map[string]interface{} {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(s.buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var rec map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("invalid JSON log line %q: %v", line, err)
+		}
+		out = append(out, rec)
+	}
+	return out
+}
+
+func (s *logSink) find(t *testing.T, msg string) map[string]interface{} {
+	t.Helper()
+	for _, rec := range s.records(t) {
+		if rec["msg"] == msg {
+			return rec
+		}
+	}
+	return nil
+}
+
+func useJSONLogger(t *testing.T) *logSink {
+	t.Helper()
+	sink := &logSink{}
+	flowable.SetLogger(flowable.NewJSONLogger(sink, slog.LevelDebug))
+	t.Cleanup(func() { flowable.SetLogger(nil) })
+	return sink
+}
+
+func jobServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	var once sync.Once
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/external-job-api/acquire/jobs" {
+			body := `[]`
+			once.Do(func() { body = `[{"id":"job-1","processInstanceId":"pi-1"}]` })
+			io.WriteString(w, body)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestLogging_JobScopedRecords(t *testing.T) {
+	sink := useJSONLogger(t)
+	srv := jobServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		flowable.SubscribeContext(ctx, flowable.AcquireRequest{Topic: "orders", WorkerId: "w1", URL: srv.URL, Interval: 10 * time.Millisecond},
+			func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+				flowable.LoggerFromContext(ctx).Info("inside handler")
+				return flowable.HandlerSuccess, nil
+			})
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for sink.find(t, "job reported") == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, msg := range []string{"inside handler", "job handled", "job reported"} {
+		rec := sink.find(t, msg)
+		if rec == nil {
+			t.Fatalf("no %q record in %v", msg, sink.records(t))
+		}
+		for key, want := range map[string]string{"topic": "orders", "workerId": "w1", "jobId": "job-1", "processInstanceId": "pi-1"} {
+			if rec[key] != want {
+				t.Errorf("%q: expected %s=%s, got %v", msg, key, want, rec[key])
+			}
+		}
+	}
+	if rec := sink.find(t, "job handled"); rec["outcome"] != "success" || rec["component"] != "handler" || rec["duration"] == nil {
+		t.Errorf("unexpected job handled record %v", rec)
+	}
+	if rec := sink.find(t, "job reported"); rec["outcome"] != "complete" || rec["component"] != "report" || rec["status"] != float64(204) {
+		t.Errorf("unexpected job reported record %v", rec)
+	}
+}
+
+func TestLogging_ComponentLevelAndDisable(t *testing.T) {
+	sink := useJSONLogger(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `[]`)
+	}))
+	defer other.Close()
+	req := flowable.AcquireRequest{Topic: "t", URL: srv.URL, URLs: []string{other.URL}}
+
+	flowable.SetLogLevel(flowable.ComponentAcquire, slog.LevelError)
+	defer flowable.SetLogLevel(flowable.ComponentAcquire, slog.LevelInfo)
+	if _, _, _, err := flowable.Acquire_jobs(req); err != nil {
+		t.Fatal(err)
+	}
+	if rec := sink.find(t, "node failed, trying next node"); rec != nil {
+		t.Fatalf("expected the warning to be filtered, got %v", rec)
+	}
+
+	flowable.SetLogLevel(flowable.ComponentAcquire, slog.LevelInfo)
+	failing := func() *httptest.Server {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		t.Cleanup(s.Close)
+		return s
+	}
+	if _, _, _, err := flowable.Acquire_jobs(flowable.AcquireRequest{Topic: "t", URL: failing().URL, URLs: []string{other.URL}}); err != nil {
+		t.Fatal(err)
+	}
+	if rec := sink.find(t, "node failed, trying next node"); rec == nil || rec["level"] != "WARN" || rec["component"] != "acquire" {
+		t.Fatalf("expected a warning from the acquire component, got %v", rec)
+	}
+
+	before := len(sink.records(t))
+	flowable.SetEnableLogging(false)
+	defer flowable.SetEnableLogging(true)
+	if _, _, _, err := flowable.Acquire_jobs(flowable.AcquireRequest{Topic: "t", URL: failing().URL, URLs: []string{other.URL}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := sink.records(t); len(got) != before {
+		t.Fatalf("expected no records while logging is disabled, got %v", got[before:])
+	}
+}
+
+func TestLogging_DeprecatedEnableLoggingIsHonoured(t *testing.T) {
+	sink := useJSONLogger(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `[]`)
+	}))
+	defer other.Close()
+
+	flowable.EnableLogging = false
+	defer func() { flowable.EnableLogging = true }()
+	if _, _, _, err := flowable.Acquire_jobs(flowable.AcquireRequest{Topic: "t", URL: srv.URL, URLs: []string{other.URL}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := sink.records(t); len(got) != 0 {
+		t.Fatalf("expected no records while EnableLogging is false, got %v", got)
+	}
+}
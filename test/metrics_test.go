@@ -0,0 +1,132 @@
+package worker_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/flowable/flowable-external-client-golang/flowable"
+)
+
+func scrapeMetrics(t *testing.T, url string) string {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != flowable.MetricsContentType {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestMetrics_SubscribeCountsJobs(t *testing.T) {
+	flowable.SetEnableLogging(false)
+	t.Cleanup(func() { flowable.SetEnableLogging(true) })
+	var once sync.Once
+	reported := make(chan struct{}, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/external-job-api/acquire/jobs":
+			body := `[]`
+			once.Do(func() { body = `[{"id":"job-1"},{"id":"job-2"}]` })
+			io.WriteString(w, body)
+		case strings.HasSuffix(r.URL.Path, "/job-2/fail"):
+			w.WriteHeader(http.StatusNotFound)
+			reported <- struct{}{}
+		default:
+			w.WriteHeader(http.StatusNoContent)
+			reported <- struct{}{}
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		flowable.SubscribeContext(ctx, flowable.AcquireRequest{Topic: "metrics-topic", WorkerId: "w", URL: srv.URL, Interval: 5 * time.Millisecond},
+			func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+				if strings.Contains(body, "job-2") {
+					return flowable.HandlerFail, nil
+				}
+				return flowable.HandlerSuccess, nil
+			})
+	}()
+	<-reported
+	<-reported
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	<-stopped
+
+	metricsSrv, err := flowable.ServeMetrics("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer metricsSrv.Close()
+	out := scrapeMetrics(t, "http://"+metricsSrv.Addr+"/metrics")
+
+	for _, want := range []string{
+		"# TYPE flowable_jobs_acquired_total counter",
+		`flowable_jobs_acquired_total{topic="metrics-topic"} 2`,
+		`flowable_jobs_reported_total{topic="metrics-topic",outcome="complete"} 1`,
+		`flowable_report_errors_total{topic="metrics-topic",outcome="fail"} 1`,
+		`flowable_lock_lost_total{topic="metrics-topic"} 1`,
+		`flowable_jobs_in_flight{topic="metrics-topic"} 0`,
+		`flowable_handler_duration_seconds_bucket{topic="metrics-topic",le="+Inf"} 2`,
+		`flowable_handler_duration_seconds_count{topic="metrics-topic"} 2`,
+		"# TYPE flowable_acquire_duration_seconds histogram",
+		`flowable_acquire_empty_polls_total{topic="metrics-topic"}`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics output does not contain %q", want)
+		}
+	}
+	if t.Failed() {
+		t.Log(out)
+	}
+}
+
+func TestMetrics_AcquireErrors(t *testing.T) {
+	flowable.SetEnableLogging(false)
+	t.Cleanup(func() { flowable.SetEnableLogging(true) })
+	polled := make(chan struct{}, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		select {
+		case polled <- struct{}{}:
+		default:
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		flowable.SubscribeContext(ctx, flowable.AcquireRequest{Topic: "metrics-errors", WorkerId: "w", URL: srv.URL, Interval: 5 * time.Millisecond},
+			func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+				return flowable.HandlerFail, nil
+			})
+	}()
+	<-polled
+	<-polled
+	cancel()
+	<-stopped
+
+	rec := httptest.NewRecorder()
+	flowable.MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), `flowable_acquire_errors_total{topic="metrics-errors"} `) {
+		t.Fatalf("expected acquire errors to be counted:\n%s", rec.Body.String())
+	}
+}
@@ -0,0 +1,83 @@
+package worker_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flowable/flowable-external-client-golang/flowable"
+)
+
+func TestExtractVariablesFromBody_LongBeyondFloatPrecision(t *testing.T) {
+	body := `{"variables":[{"name":"id","type":"long","value":9007199254740993}]}`
+	vars, err := flowable.ExtractVariablesFromBody(body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v, ok := vars[0].Value.(int64); !ok || v != 9007199254740993 {
+		t.Fatalf("expected int64 9007199254740993, got %T %v", vars[0].Value, vars[0].Value)
+	}
+	if got := flowable.GetVar(vars, "id"); got != "9007199254740993" {
+		t.Fatalf("expected 9007199254740993, got %q", got)
+	}
+}
+
+func TestExtractVariablesFromBody_BigDecimal(t *testing.T) {
+	body := `{"variables":{"amount":{"type":"bigdecimal","value":12345678901234567890.123456789}}}`
+	vars, err := flowable.ExtractVariablesFromBody(body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := vars[0].Value.(json.Number); !ok {
+		t.Fatalf("expected json.Number, got %T", vars[0].Value)
+	}
+	if got := flowable.GetVar(vars, "amount"); got != "12345678901234567890.123456789" {
+		t.Fatalf("expected exact decimal, got %q", got)
+	}
+}
+
+func TestExtractVariablesFromBody_NumbersRoundTrip(t *testing.T) {
+	body := `{"variables":[` +
+		`{"name":"l","type":"long","value":-9223372036854775808},` +
+		`{"name":"i","type":"integer","value":7},` +
+		`{"name":"d","type":"double","value":0.1},` +
+		`{"name":"b","type":"bigdecimal","value":0.10000000000000000000001},` +
+		`{"name":"j","type":"json","value":{"n":18446744073709551615,"m":[1,2.5]}}]}`
+	vars, err := flowable.ExtractVariablesFromBody(body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out, err := json.Marshal(flowable.HandlerResult{Status: flowable.HandlerSuccess, Variables: vars})
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	for _, want := range []string{
+		`"value":-9223372036854775808`,
+		`"value":7`,
+		`"value":0.1`,
+		`"value":0.10000000000000000000001`,
+		`"n":18446744073709551615`,
+		`"m":[1,2.5]`,
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Fatalf("expected %s in %s", want, out)
+		}
+	}
+}
+
+func TestAcquireJobs_KeepsLongNumbers(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `[{"id":"job-1","retries":9007199254740993}]`)
+	}))
+	defer srv.Close()
+	jobs, _, _, err := flowable.Acquire_jobs(flowable.AcquireRequest{Topic: "t", WorkerId: "w", URL: srv.URL})
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("unexpected acquire: %v %v", jobs, err)
+	}
+	if got, ok := jobs[0].(map[string]interface{})["retries"].(json.Number); !ok || got != "9007199254740993" {
+		t.Fatalf("expected json.Number 9007199254740993, got %T %v", jobs[0].(map[string]interface{})["retries"], jobs[0].(map[string]interface{})["retries"])
+	}
+}
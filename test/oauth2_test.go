@@ -0,0 +1,144 @@
+package worker_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/flowable/flowable-external-client-golang/flowable"
+)
+
+// tokenServer is a stand-in OAuth2 token endpoint issuing token-1, token-2, ...
+type tokenServer struct {
+	*httptest.Server
+	issued    int32
+	expiresIn int
+}
+
+func newTokenServer(t *testing.T, expiresIn int) *tokenServer {
+	t.Helper()
+	ts := &tokenServer{expiresIn: expiresIn}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "client" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			io.WriteString(w, `{"error":"invalid_client"}`)
+			return
+		}
+		r.ParseForm()
+		if r.Form.Get("grant_type") != "client_credentials" || r.Form.Get("scope") != "jobs" {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"error":"invalid_request"}`)
+			return
+		}
+		n := atomic.AddInt32(&ts.issued, 1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":%d}`, n, ts.expiresIn)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// apiServer accepts requests carrying one of the allowed bearer tokens and records every token seen.
+func apiServer(t *testing.T, allowed func(token string) bool) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		mu.Lock()
+		seen = append(seen, token)
+		mu.Unlock()
+		if !allowed(token) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		io.WriteString(w, `{"data":[],"total":0}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), seen...)
+	}
+}
+
+func useAuthenticator(t *testing.T, a flowable.Authenticator) {
+	t.Helper()
+	flowable.SetAuthenticator(a)
+	t.Cleanup(func() { flowable.SetAuthenticator(nil) })
+}
+
+func TestOAuth2_CachesToken(t *testing.T) {
+	ts := newTokenServer(t, 3600)
+	api, seen := apiServer(t, func(token string) bool { return token == "token-1" })
+	useAuthenticator(t, flowable.NewOAuth2ClientCredentials(ts.URL, "client", "secret", "jobs"))
+
+	for i := 0; i < 3; i++ {
+		status, _, err := flowable.List_jobs(api.URL)
+		if err != nil || status != 200 {
+			t.Fatalf("List_jobs: status=%d err=%v", status, err)
+		}
+	}
+	if atomic.LoadInt32(&ts.issued) != 1 {
+		t.Fatalf("expected 1 token request, got %d", ts.issued)
+	}
+	if got := seen(); len(got) != 3 {
+		t.Fatalf("expected 3 API calls, got %v", got)
+	}
+}
+
+func TestOAuth2_RefreshesAheadOfExpiry(t *testing.T) {
+	ts := newTokenServer(t, 60)
+	api, seen := apiServer(t, func(token string) bool { return true })
+	auth := flowable.NewOAuth2ClientCredentials(ts.URL, "client", "secret", "jobs")
+	auth.RefreshBefore = 2 * time.Minute // longer than the token lifetime: every call refreshes
+	useAuthenticator(t, auth)
+
+	flowable.List_jobs(api.URL)
+	flowable.List_jobs(api.URL)
+	if got := seen(); len(got) != 2 || got[0] != "token-1" || got[1] != "token-2" {
+		t.Fatalf("expected a fresh token per call, got %v", got)
+	}
+}
+
+func TestOAuth2_RetriesOnceOn401(t *testing.T) {
+	ts := newTokenServer(t, 3600)
+	api, seen := apiServer(t, func(token string) bool { return token == "token-2" })
+	useAuthenticator(t, flowable.NewOAuth2ClientCredentials(ts.URL, "client", "secret", "jobs"))
+
+	status, _, err := flowable.List_jobs(api.URL)
+	if err != nil || status != 200 {
+		t.Fatalf("List_jobs: status=%d err=%v", status, err)
+	}
+	if got := seen(); len(got) != 2 || got[0] != "token-1" || got[1] != "token-2" {
+		t.Fatalf("expected retry with a new token, got %v", got)
+	}
+
+	// A server rejecting every token is retried only once.
+	reject, rejected := apiServer(t, func(token string) bool { return false })
+	status, _, _ = flowable.List_jobs(reject.URL)
+	if status != 401 || len(rejected()) != 2 {
+		t.Fatalf("expected a single retry ending in 401, got status %d after %v", status, rejected())
+	}
+}
+
+func TestOAuth2_TokenEndpointError(t *testing.T) {
+	ts := newTokenServer(t, 3600)
+	api, seen := apiServer(t, func(token string) bool { return true })
+	useAuthenticator(t, flowable.NewOAuth2ClientCredentials(ts.URL, "client", "wrong", "jobs"))
+
+	_, _, err := flowable.List_jobs(api.URL)
+	if err == nil || !strings.Contains(err.Error(), "invalid_client") {
+		t.Fatalf("expected invalid_client error, got %v", err)
+	}
+	if len(seen()) != 0 {
+		t.Fatalf("expected no API call without a token")
+	}
+}
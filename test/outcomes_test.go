@@ -0,0 +1,154 @@
+package worker_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/flowable/flowable-external-client-golang/flowable"
+)
+
+type insufficientFunds struct{ missing int }
+
+func (e *insufficientFunds) Error() string { return fmt.Sprintf("missing %d", e.missing) }
+
+func TestErrorOutcome_BuiltIns(t *testing.T) {
+	vars := []flowable.HandlerVariable{{Name: "reason", Type: "string", Value: "low"}}
+
+	status, res := flowable.ErrorOutcome(fmt.Errorf("wrapped: %w", flowable.BPMNError("INSUFFICIENT_FUNDS", vars)))
+	if status != flowable.HandlerBPMNError || res.ErrorCode != "INSUFFICIENT_FUNDS" || len(res.Variables) != 1 {
+		t.Fatalf("unexpected bpmn error outcome: %v %+v", status, res)
+	}
+
+	status, res = flowable.ErrorOutcome(flowable.Terminate())
+	if status != flowable.HandlerCMMNTerminate || res.Variables == nil {
+		t.Fatalf("unexpected terminate outcome: %v %+v", status, res)
+	}
+
+	status, res = flowable.ErrorOutcome(flowable.Retryable(errors.New("busy"), 90*time.Second))
+	if status != flowable.HandlerFail || res.RetryTimeout != "PT90S" || res.Retries != nil || res.ErrorMessage != "busy" {
+		t.Fatalf("unexpected retryable outcome: %v %+v", status, res)
+	}
+
+	status, res = flowable.ErrorOutcome(flowable.Permanent(errors.New("invalid")))
+	if status != flowable.HandlerFail || res.Retries == nil || *res.Retries != 0 {
+		t.Fatalf("unexpected permanent outcome: %v %+v", status, res)
+	}
+
+	status, res = flowable.ErrorOutcome(errors.New("plain"))
+	if status != flowable.HandlerFail || res.ErrorMessage != "plain" || res.Retries != nil || res.RetryTimeout != "" {
+		t.Fatalf("unexpected plain outcome: %v %+v", status, res)
+	}
+
+	status, _ = flowable.ErrorOutcome(nil)
+	if status != flowable.HandlerSuccess {
+		t.Fatalf("expected success for nil error, got %v", status)
+	}
+}
+
+func TestErrorOutcome_NilCause(t *testing.T) {
+	status, res := flowable.ErrorOutcome(flowable.Retryable(nil, time.Minute))
+	if status != flowable.HandlerFail || res.ErrorMessage != "retryable error" || res.RetryTimeout != "PT60S" {
+		t.Fatalf("unexpected retryable outcome: %v %+v", status, res)
+	}
+	status, res = flowable.ErrorOutcome(flowable.Permanent(nil))
+	if status != flowable.HandlerFail || res.ErrorMessage != "permanent error" || res.Retries == nil || *res.Retries != 0 {
+		t.Fatalf("unexpected permanent outcome: %v %+v", status, res)
+	}
+}
+
+func TestErrorPolicy_MatchError(t *testing.T) {
+	policy := flowable.NewErrorPolicy(
+		flowable.MatchError(func(e *insufficientFunds) error {
+			return flowable.BPMNError("INSUFFICIENT_FUNDS", []flowable.HandlerVariable{{Name: "missing", Type: "integer", Value: e.missing}})
+		}),
+	)
+	status, res := policy.Outcome(fmt.Errorf("charge: %w", &insufficientFunds{missing: 5}))
+	if status != flowable.HandlerBPMNError || res.ErrorCode != "INSUFFICIENT_FUNDS" || flowable.GetVar(res.Variables, "missing") != "5" {
+		t.Fatalf("unexpected outcome: %v %+v", status, res)
+	}
+	status, _ = policy.Outcome(errors.New("other"))
+	if status != flowable.HandlerFail {
+		t.Fatalf("expected fail for unmatched error, got %v", status)
+	}
+}
+
+// outcomeServer serves a single job on the first acquire and records the
+// endpoint and payload of the report call.
+func outcomeServer(t *testing.T) (*httptest.Server, <-chan string, <-chan map[string]interface{}) {
+	t.Helper()
+	paths := make(chan string, 1)
+	payloads := make(chan map[string]interface{}, 1)
+	var once sync.Once
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/external-job-api/acquire/jobs" {
+			served := false
+			once.Do(func() {
+				served = true
+				io.WriteString(w, `[{"id":"job-1","variables":[]}]`)
+			})
+			if !served {
+				io.WriteString(w, `[]`)
+			}
+			return
+		}
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.WriteHeader(http.StatusNoContent)
+		paths <- r.URL.Path
+		payloads <- payload
+	}))
+	t.Cleanup(srv.Close)
+	return srv, paths, payloads
+}
+
+func TestErrorHandler_RoutesToReportEndpoint(t *testing.T) {
+	flowable.SetEnableLogging(false)
+	defer flowable.SetEnableLogging(true)
+
+	cases := []struct {
+		err  error
+		path string
+		key  string
+		want interface{}
+	}{
+		{flowable.BPMNError("CODE", nil), "/external-job-api/acquire/jobs/job-1/bpmnError", "errorCode", "CODE"},
+		{flowable.Terminate(), "/external-job-api/acquire/jobs/job-1/cmmnTerminate", "workerId", "w1"},
+		{flowable.Permanent(errors.New("bad")), "/external-job-api/acquire/jobs/job-1/fail", "retries", float64(0)},
+		{flowable.Retryable(errors.New("later"), time.Minute), "/external-job-api/acquire/jobs/job-1/fail", "retryTimeout", "PT60S"},
+		{nil, "/external-job-api/acquire/jobs/job-1/complete", "workerId", "w1"},
+	}
+	for _, tc := range cases {
+		srv, paths, payloads := outcomeServer(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		req := flowable.AcquireRequest{Topic: "t", WorkerId: "w1", URL: srv.URL, Interval: 10 * time.Millisecond}
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			flowable.SubscribeContext(ctx, req, flowable.ErrorHandler(func(ctx context.Context, status int, body string) ([]flowable.HandlerVariable, error) {
+				return nil, tc.err
+			}))
+		}()
+		select {
+		case path := <-paths:
+			payload := <-payloads
+			if path != tc.path {
+				t.Fatalf("%v: expected %s, got %s", tc.err, tc.path, path)
+			}
+			if payload[tc.key] != tc.want {
+				t.Fatalf("%v: expected %s=%v, got %v", tc.err, tc.key, tc.want, payload)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%v: timeout waiting for report", tc.err)
+		}
+		cancel()
+		<-stopped
+	}
+}
@@ -0,0 +1,224 @@
+package worker_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/flowable/flowable-external-client-golang/flowable"
+)
+
+// acquireLog records the acquires and reports received by a reloadServer.
+type acquireLog struct {
+	mu       sync.Mutex
+	acquires []string // topic lockDuration user
+	reports  []string // path user
+}
+
+func (l *acquireLog) has(list func(*acquireLog) []string, want string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, s := range list(l) {
+		if s == want {
+			return true
+		}
+	}
+	return false
+}
+
+func acquiresOf(l *acquireLog) []string { return l.acquires }
+func reportsOf(l *acquireLog) []string  { return l.reports }
+
+// reloadServer hands out job-a once on topic "a" and records the requests.
+func reloadServer(t *testing.T) (*httptest.Server, *acquireLog) {
+	t.Helper()
+	l := &acquireLog{}
+	var once sync.Once
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _, _ := r.BasicAuth()
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if r.URL.Path != "/external-job-api/acquire/jobs" {
+			l.reports = append(l.reports, r.URL.Path+" "+user)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		var req flowable.AcquireRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		l.acquires = append(l.acquires, req.Topic+" "+req.LockDuration+" "+user)
+		body := `[]`
+		if req.Topic == "a" {
+			once.Do(func() { body = `[{"id":"job-a"}]` })
+		}
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, l
+}
+
+func reloadConfig(url, user, topics string) string {
+	return fmt.Sprintf("urls: [%s]\nworkerId: w\nauth: {username: %s, password: secret}\ndefaults: {interval: 5ms}\ntopics: %s\n", url, user, topics)
+}
+
+func TestReload_AppliesChangesWithoutDroppingJobs(t *testing.T) {
+	quietLogs(t)
+	useAuthReset(t)
+	srv, l := reloadServer(t)
+	path := writeConfig(t, "worker.yaml", reloadConfig(srv.URL, "u1", "[{topic: a}]"))
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handlers := map[string]flowable.ContextHandler{
+		"a": func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+			close(started)
+			<-release
+			return flowable.HandlerSuccess, nil
+		},
+		"*": func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+			return flowable.HandlerSuccess, nil
+		},
+	}
+	w := flowable.NewWorker()
+	r, err := flowable.NewReloader(context.Background(), w, path, handlers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { w.Drain(context.Background()) })
+	<-started
+	subA := w.Subscriptions()[0]
+
+	// Remove a, add b and rotate the credentials while job-a is in flight.
+	os.WriteFile(path, []byte(reloadConfig(srv.URL, "u2", "[{topic: b, lockDuration: PT1M}]")), 0o600)
+	if err := r.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "b to be acquired with the new credentials", func() bool { return l.has(acquiresOf, "b PT1M u2") })
+	if subA.Status().Acquiring {
+		t.Fatal("expected the removed topic to stop acquiring")
+	}
+	select {
+	case <-subA.Done():
+		t.Fatal("the removed subscription ended before its job finished")
+	default:
+	}
+	close(release)
+	<-subA.Done()
+	if !l.has(reportsOf, "/external-job-api/acquire/jobs/job-a/complete u2") {
+		t.Fatalf("expected job-a to be reported with the new credentials, got %v", l.reports)
+	}
+
+	// Change the settings of b in place.
+	os.WriteFile(path, []byte(reloadConfig(srv.URL, "u2", "[{topic: b, lockDuration: PT2M, concurrency: 3}]")), 0o600)
+	if err := r.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the new lock duration", func() bool { return l.has(acquiresOf, "b PT2M u2") })
+	subs := w.Subscriptions()
+	if len(subs) != 1 || subs[0].Name() != "b" || subs[0].Status().Concurrency != 3 {
+		t.Fatalf("expected b to be updated in place, got %+v", subs)
+	}
+}
+
+func TestReload_InvalidConfigKeepsTheRunningOne(t *testing.T) {
+	sink := useJSONLogger(t)
+	useAuthReset(t)
+	srv, l := reloadServer(t)
+	path := writeConfig(t, "worker.yaml", reloadConfig(srv.URL, "u1", "[{topic: b}]"))
+	w := flowable.NewWorker()
+	r, err := flowable.NewReloader(context.Background(), w, path, map[string]flowable.ContextHandler{
+		"b": func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+			return flowable.HandlerSuccess, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { w.Drain(context.Background()) })
+	before := r.Config()
+
+	for name, content := range map[string]string{
+		"invalid":    reloadConfig(srv.URL, "u2", "[{topic: b, lockDuration: soon}]"),
+		"no handler": reloadConfig(srv.URL, "u2", "[{topic: b}, {topic: c}]"),
+	} {
+		os.WriteFile(path, []byte(content), 0o600)
+		if err := r.Reload(context.Background()); err == nil {
+			t.Fatalf("%s: expected the reload to fail", name)
+		}
+	}
+	if r.Config() != before || len(w.Subscriptions()) != 1 {
+		t.Fatal("expected the running configuration to be kept")
+	}
+	l.mu.Lock()
+	l.acquires = nil
+	l.mu.Unlock()
+	waitFor(t, "an acquire with the old credentials", func() bool { return l.has(acquiresOf, "b "+flowable.DefaultLockDuration+" u1") })
+	if sink.find(t, "configuration rejected, keeping the running one") == nil {
+		t.Fatal("expected the rejection to be logged")
+	}
+}
+
+func TestReload_FailedSubscribeRestoresCredentials(t *testing.T) {
+	quietLogs(t)
+	useAuthReset(t)
+	srv, l := reloadServer(t)
+	path := writeConfig(t, "worker.yaml", reloadConfig(srv.URL, "u1", "[{topic: b}]"))
+	w := flowable.NewWorker()
+	r, err := flowable.NewReloader(context.Background(), w, path, map[string]flowable.ContextHandler{
+		"*": func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+			return flowable.HandlerSuccess, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "an acquire", func() bool { return l.has(acquiresOf, "b "+flowable.DefaultLockDuration+" u1") })
+	if err := w.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	before := r.Config()
+
+	// The draining worker refuses the new topic.
+	os.WriteFile(path, []byte(reloadConfig(srv.URL, "u2", "[{topic: b, lockDuration: PT1M}, {topic: c}]")), 0o600)
+	if err := r.Reload(context.Background()); !errors.Is(err, flowable.ErrDraining) {
+		t.Fatalf("expected ErrDraining, got %v", err)
+	}
+	if r.Config() != before || flowable.AuthUser != "u1" {
+		t.Fatalf("expected the running configuration and credentials to be kept, got user %q", flowable.AuthUser)
+	}
+}
+
+func TestReload_WatchPicksUpFileChanges(t *testing.T) {
+	quietLogs(t)
+	useAuthReset(t)
+	srv, l := reloadServer(t)
+	path := writeConfig(t, "worker.yaml", reloadConfig(srv.URL, "u1", "[{topic: b}]"))
+	w := flowable.NewWorker()
+	r, err := flowable.NewReloader(context.Background(), w, path, map[string]flowable.ContextHandler{
+		"*": func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+			return flowable.HandlerSuccess, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { w.Drain(context.Background()) })
+	ctx, cancel := context.WithCancel(context.Background())
+	watching := make(chan struct{})
+	go func() {
+		r.Watch(ctx, 5*time.Millisecond)
+		close(watching)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-watching
+	})
+
+	os.WriteFile(path, []byte(reloadConfig(srv.URL, "u1", "[{topic: b}, {topic: watched}]")), 0o600)
+	waitFor(t, "the new topic", func() bool { return l.has(acquiresOf, "watched "+flowable.DefaultLockDuration+" u1") })
+}
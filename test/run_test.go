@@ -0,0 +1,154 @@
+//go:build unix
+
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/flowable/flowable-external-client-golang/flowable"
+)
+
+// runWorker runs w with RunSignals in the background and returns the signal
+// channel and the exit code channel.
+func runWorker(t *testing.T, w *flowable.Worker) (chan<- os.Signal, <-chan int) {
+	t.Helper()
+	sigs := make(chan os.Signal, 1)
+	code := make(chan int, 1)
+	go func() { code <- flowable.RunSignals(context.Background(), w, sigs) }()
+	return sigs, code
+}
+
+func TestRun_SIGTERMDrainsInFlightJobs(t *testing.T) {
+	quietLogs(t)
+	srv, reports := blockingJobServer(t, http.StatusOK)
+	w := flowable.NewWorker()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	if _, err := w.Subscribe(context.Background(), flowable.AcquireRequest{Topic: "run", WorkerId: "w", URL: srv.URL, Interval: 5 * time.Millisecond},
+		func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+			close(started)
+			<-release
+			return flowable.HandlerSuccess, nil
+		}); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	sigs, code := runWorker(t, w)
+	sigs <- syscall.SIGTERM
+	waitFor(t, "draining", w.Draining)
+	close(release)
+	if c := <-code; c != flowable.ExitOK {
+		t.Fatalf("expected exit code %d, got %d", flowable.ExitOK, c)
+	}
+	if got := <-reports; got != "/external-job-api/acquire/jobs/job-1/complete" {
+		t.Fatalf("unexpected report %s", got)
+	}
+}
+
+func TestRun_DrainTimeoutAndInterrupt(t *testing.T) {
+	quietLogs(t)
+	for _, tc := range []struct {
+		name      string
+		interrupt bool
+		want      int
+	}{
+		{"timeout", false, flowable.ExitDrainTimeout},
+		{"second signal", true, flowable.ExitInterrupted},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, _ := blockingJobServer(t, http.StatusOK)
+			w := flowable.NewWorker()
+			w.DrainTimeout = 50 * time.Millisecond
+			if tc.interrupt {
+				w.DrainTimeout = time.Minute
+			}
+			started := make(chan struct{})
+			sub, err := w.Subscribe(context.Background(), flowable.AcquireRequest{Topic: "stuck", WorkerId: "w", URL: srv.URL, Interval: 5 * time.Millisecond},
+				func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+					close(started)
+					<-ctx.Done()
+					return flowable.HandlerFail, nil
+				})
+			if err != nil {
+				t.Fatal(err)
+			}
+			<-started
+			sigs, code := runWorker(t, w)
+			sigs <- syscall.SIGINT
+			if tc.interrupt {
+				waitFor(t, "draining", w.Draining)
+				sigs <- syscall.SIGINT
+			}
+			if c := <-code; c != tc.want {
+				t.Fatalf("expected exit code %d, got %d", tc.want, c)
+			}
+			<-sub.Done()
+		})
+	}
+}
+
+func TestRun_SIGHUPReloads(t *testing.T) {
+	sink := useJSONLogger(t)
+	w := flowable.NewWorker()
+	reloads := make(chan struct{}, 2)
+	w.Reload = func(ctx context.Context) error {
+		reloads <- struct{}{}
+		if len(reloads) == 2 {
+			return errors.New("bad config")
+		}
+		return nil
+	}
+	sigs, code := runWorker(t, w)
+	sigs <- syscall.SIGHUP
+	sigs <- syscall.SIGHUP
+	waitFor(t, "the failed reload", func() bool { return sink.find(t, "reload failed") != nil })
+	if rec := sink.find(t, "reload failed"); rec["error"] != "bad config" {
+		t.Fatalf("unexpected record %v", rec)
+	}
+	if sink.find(t, "reloaded") == nil {
+		t.Fatal("expected the first reload to be logged")
+	}
+	sigs <- syscall.SIGTERM
+	if c := <-code; c != flowable.ExitOK {
+		t.Fatalf("expected exit code %d, got %d", flowable.ExitOK, c)
+	}
+}
+
+func TestRun_SIGUSR1DumpsState(t *testing.T) {
+	sink := useJSONLogger(t)
+	srv, _ := blockingJobServer(t, http.StatusOK)
+	w := flowable.NewWorker()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	if _, err := w.Subscribe(context.Background(), flowable.AcquireRequest{Topic: "dump", WorkerId: "w", URL: srv.URL, Interval: 5 * time.Millisecond},
+		func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+			close(started)
+			<-release
+			return flowable.HandlerSuccess, nil
+		}); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	sigs, code := runWorker(t, w)
+	sigs <- syscall.SIGUSR1
+	waitFor(t, "the goroutine dump", func() bool { return sink.find(t, "goroutine dump") != nil })
+	jobs := sink.find(t, "in-flight jobs")
+	if jobs["count"] != float64(1) || !strings.Contains(mustJSON(t, jobs["jobs"]), `"jobId":"job-1"`) {
+		t.Fatalf("unexpected in-flight record %v", jobs)
+	}
+	if stacks, _ := sink.find(t, "goroutine dump")["stacks"].(string); !strings.Contains(stacks, "goroutine ") {
+		t.Fatalf("expected goroutine stacks, got %q", stacks)
+	}
+	close(release)
+	sigs <- syscall.SIGTERM
+	if c := <-code; c != flowable.ExitOK {
+		t.Fatalf("expected exit code %d, got %d", flowable.ExitOK, c)
+	}
+}
@@ -0,0 +1,148 @@
+package worker_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/flowable/flowable-external-client-golang/flowable"
+)
+
+// notifySocket listens on a NOTIFY_SOCKET and collects the messages sent to it.
+func notifySocket(t *testing.T) func() []string {
+	t.Helper()
+	// Unix socket paths are limited in length, so avoid the long t.TempDir().
+	dir, err := os.MkdirTemp("", "sd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+
+	var mu sync.Mutex
+	var msgs []string
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			msgs = append(msgs, string(buf[:n]))
+			mu.Unlock()
+		}
+	}()
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), msgs...)
+	}
+}
+
+func countMessages(msgs []string, prefix string) int {
+	n := 0
+	for _, m := range msgs {
+		if strings.HasPrefix(m, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestSDNotify_WithoutSocket(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if sent, err := flowable.SDNotify("READY=1"); sent || err != nil {
+		t.Fatalf("expected a no-op, got %v %v", sent, err)
+	}
+}
+
+func TestSystemd_ReadyStatusWatchdogAndStopping(t *testing.T) {
+	quietLogs(t)
+	msgs := notifySocket(t)
+	t.Setenv("WATCHDOG_USEC", "40000")
+	var once sync.Once
+	var hang atomic.Bool
+	unblock := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/external-job-api/acquire/jobs" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if hang.Load() {
+			io.Copy(io.Discard, r.Body)
+			<-unblock
+		}
+		body := `[]`
+		once.Do(func() { body = `[{"id":"job-1"}]` })
+		io.WriteString(w, body)
+	}))
+	defer srv.Close()
+	defer close(unblock)
+
+	w := flowable.NewWorker()
+	release := make(chan struct{})
+	_, err := w.Subscribe(context.Background(), flowable.AcquireRequest{Topic: "sd", WorkerId: "w", URL: srv.URL, Interval: 5 * time.Millisecond, Concurrency: 2},
+		func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+			<-release
+			return flowable.HandlerSuccess, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	notifierDone := make(chan struct{})
+	go func() {
+		defer close(notifierDone)
+		w.NotifySystemd(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-notifierDone
+	}()
+
+	waitFor(t, "READY=1", func() bool { return countMessages(msgs(), "READY=1") == 1 })
+	waitFor(t, "an in-flight status", func() bool { return countMessages(msgs(), "STATUS=1 subscriptions, 1 jobs in flight") == 1 })
+
+	// A job running for longer than the watchdog timeout does not stop the pings.
+	time.Sleep(100 * time.Millisecond)
+	pinged := countMessages(msgs(), "WATCHDOG=1")
+	waitFor(t, "pings during a long job", func() bool { return countMessages(msgs(), "WATCHDOG=1") >= pinged+2 })
+
+	// An acquire that hangs for longer than the watchdog timeout stops them.
+	hang.Store(true)
+	time.Sleep(80 * time.Millisecond)
+	stuck := countMessages(msgs(), "WATCHDOG=1")
+	time.Sleep(100 * time.Millisecond)
+	if n := countMessages(msgs(), "WATCHDOG=1"); n != stuck {
+		t.Fatalf("expected no watchdog pings while the acquire hangs, got %d more", n-stuck)
+	}
+
+	close(release)
+	drained := make(chan error, 1)
+	go func() { drained <- w.Drain(context.Background()) }()
+	waitFor(t, "STOPPING=1", func() bool { return countMessages(msgs(), "STOPPING=1") == 1 })
+	hang.Store(false)
+	unblock <- struct{}{}
+	if err := <-drained; err != nil {
+		t.Fatal(err)
+	}
+	if n := countMessages(msgs(), "READY=1"); n != 1 {
+		t.Fatalf("expected READY=1 once, got %d", n)
+	}
+}
@@ -0,0 +1,192 @@
+package worker_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/flowable/flowable-external-client-golang/flowable"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns PEM encoded certificate and key signed by the CA.
+func (ca *testCA) issue(t *testing.T, cn string, dnsNames []string, client bool) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	usage := x509.ExtKeyUsageServerAuth
+	if client {
+		usage = x509.ExtKeyUsageClientAuth
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	if !client && len(dnsNames) == 0 {
+		tmpl.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// mtlsServer starts a TLS server requiring client certificates from ca and
+// answering with the common name of the client certificate.
+func mtlsServer(t *testing.T, ca *testCA, dnsNames []string) *httptest.Server {
+	t.Helper()
+	certPEM, keyPEM := ca.issue(t, "server", dnsNames, false)
+	serverCert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{serverCert}, ClientCAs: pool, ClientAuth: tls.RequireAndVerifyClientCert}
+	srv.Config.SetKeepAlivesEnabled(false)
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func restoreHTTPClient(t *testing.T) {
+	t.Helper()
+	prev := flowable.HTTPClient
+	t.Cleanup(func() { flowable.SetHTTPClient(prev) })
+}
+
+func TestConfigureTLS_MutualTLSWithRotation(t *testing.T) {
+	ca := newTestCA(t)
+	srv := mtlsServer(t, ca, nil)
+	dir := t.TempDir()
+	caFile, certFile, keyFile := filepath.Join(dir, "ca.pem"), filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeFile(t, caFile, ca.pem)
+	certPEM, keyPEM := ca.issue(t, "client-1", nil, true)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+
+	restoreHTTPClient(t)
+	err := flowable.ConfigureTLS(flowable.TLSOptions{
+		CAFile:              caFile,
+		CertFile:            certFile,
+		KeyFile:             keyFile,
+		MinVersion:          tls.VersionTLS13,
+		ReloadCheckInterval: time.Nanosecond,
+	})
+	if err != nil {
+		t.Fatalf("ConfigureTLS: %v", err)
+	}
+	status, body, err := flowable.List_jobs(srv.URL)
+	if err != nil || status != 200 || body != "client-1" {
+		t.Fatalf("expected client-1, got status=%d body=%q err=%v", status, body, err)
+	}
+
+	certPEM, keyPEM = ca.issue(t, "client-2-rotated", nil, true)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	_, body, err = flowable.List_jobs(srv.URL)
+	if err != nil || body != "client-2-rotated" {
+		t.Fatalf("expected rotated certificate, got body=%q err=%v", body, err)
+	}
+}
+
+func TestConfigureTLS_ServerNameOverride(t *testing.T) {
+	ca := newTestCA(t)
+	srv := mtlsServer(t, ca, []string{"flowable.internal"})
+	dir := t.TempDir()
+	caFile, certFile, keyFile := filepath.Join(dir, "ca.pem"), filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeFile(t, caFile, ca.pem)
+	certPEM, keyPEM := ca.issue(t, "client", nil, true)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+
+	restoreHTTPClient(t)
+	opts := flowable.TLSOptions{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}
+	if err := flowable.ConfigureTLS(opts); err != nil {
+		t.Fatalf("ConfigureTLS: %v", err)
+	}
+	if _, _, err := flowable.List_jobs(srv.URL); err == nil {
+		t.Fatalf("expected hostname verification error without ServerName")
+	}
+	opts.ServerName = "flowable.internal"
+	if err := flowable.ConfigureTLS(opts); err != nil {
+		t.Fatalf("ConfigureTLS: %v", err)
+	}
+	if status, _, err := flowable.List_jobs(srv.URL); err != nil || status != 200 {
+		t.Fatalf("expected success with ServerName, got status=%d err=%v", status, err)
+	}
+}
+
+func TestConfigureTLS_Errors(t *testing.T) {
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.pem")
+	writeFile(t, bad, []byte("not a certificate"))
+	if _, err := flowable.NewTLSConfig(flowable.TLSOptions{CAFile: bad}); err == nil {
+		t.Fatalf("expected error for invalid CA bundle")
+	}
+	if _, err := flowable.NewTLSConfig(flowable.TLSOptions{CertFile: bad}); err == nil {
+		t.Fatalf("expected error for CertFile without KeyFile")
+	}
+	if _, err := flowable.NewTLSConfig(flowable.TLSOptions{CertFile: bad, KeyFile: bad}); err == nil {
+		t.Fatalf("expected error for invalid key pair")
+	}
+	cfg, err := flowable.NewTLSConfig(flowable.TLSOptions{})
+	if err != nil || cfg.MinVersion != tls.VersionTLS12 {
+		t.Fatalf("expected TLS 1.2 default, got %v %v", cfg, err)
+	}
+}
@@ -0,0 +1,123 @@
+package worker_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/flowable/flowable-external-client-golang/flowable"
+)
+
+func useTracer(t *testing.T) *flowable.RecordingTracer {
+	t.Helper()
+	tr := flowable.NewRecordingTracer()
+	flowable.SetTracer(tr)
+	t.Cleanup(func() { flowable.SetTracer(nil) })
+	return tr
+}
+
+func TestTracing_SpansAndTraceParent(t *testing.T) {
+	flowable.SetEnableLogging(false)
+	t.Cleanup(func() { flowable.SetEnableLogging(true) })
+	tr := useTracer(t)
+
+	var mu sync.Mutex
+	headers := map[string]string{}
+	var once sync.Once
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		headers[r.URL.Path] = r.Header.Get("traceparent")
+		mu.Unlock()
+		if r.URL.Path == "/external-job-api/acquire/jobs" {
+			body := `[]`
+			once.Do(func() { body = `[{"id":"job-1","processInstanceId":"pi-1"}]` })
+			io.WriteString(w, body)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	var handlerSpan flowable.Span
+	done := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		flowable.SubscribeContext(ctx, flowable.AcquireRequest{Topic: "traced", WorkerId: "w", URL: srv.URL, Interval: 10 * time.Millisecond},
+			func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+				handlerSpan = flowable.SpanFromContext(ctx)
+				close(done)
+				return flowable.HandlerSuccess, nil
+			})
+	}()
+	<-done
+	deadline := time.Now().Add(5 * time.Second)
+	var report *flowable.RecordedSpan
+	for report == nil && time.Now().Before(deadline) {
+		for _, s := range tr.Spans() {
+			if s.Name == flowable.SpanReport {
+				report = &s
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-stopped
+	if report == nil {
+		t.Fatalf("no report span in %v", tr.Spans())
+	}
+
+	var acquire, handle *flowable.RecordedSpan
+	for _, s := range tr.Spans() {
+		switch {
+		case s.Name == flowable.SpanAcquire && acquire == nil:
+			acquire = &s
+		case s.Name == flowable.SpanHandle:
+			handle = &s
+		}
+	}
+	if acquire == nil || handle == nil {
+		t.Fatalf("missing acquire or handle span in %v", tr.Spans())
+	}
+	if acquire.Attributes["topic"] != "traced" || acquire.Attributes["jobs"] != 1 || acquire.Attributes["status"] != 200 {
+		t.Errorf("unexpected acquire span %+v", acquire)
+	}
+	if handle.Attributes["jobId"] != "job-1" || handle.Attributes["processInstanceId"] != "pi-1" || handle.Attributes["outcome"] != "success" {
+		t.Errorf("unexpected handle span %+v", handle)
+	}
+	if handlerSpan == nil || handlerSpan.TraceParent() != "00-"+handle.TraceID+"-"+handle.SpanID+"-01" {
+		t.Errorf("expected the handler context to carry the handle span")
+	}
+	if report.ParentID != handle.SpanID || report.TraceID != handle.TraceID || report.Attributes["outcome"] != "complete" || report.Attributes["status"] != 204 {
+		t.Errorf("expected the report span to be a child of the handle span, got %+v", report)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got, want := headers["/external-job-api/acquire/jobs/job-1/complete"], "00-"+report.TraceID+"-"+report.SpanID+"-01"; got != want {
+		t.Errorf("expected report traceparent %q, got %q", want, got)
+	}
+	if headers["/external-job-api/acquire/jobs"] == "" {
+		t.Errorf("expected a traceparent on acquire requests")
+	}
+}
+
+func TestTracing_NoTracerNoHeader(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Values("traceparent")
+		io.WriteString(w, `[]`)
+	}))
+	defer srv.Close()
+	if _, _, _, err := flowable.Acquire_jobs(flowable.AcquireRequest{Topic: "t", URL: srv.URL}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("expected no traceparent header, got %v", got)
+	}
+}
@@ -0,0 +1,68 @@
+package worker_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flowable/flowable-external-client-golang/flowable"
+)
+
+func TestDefaultHTTPClientTransport(t *testing.T) {
+	transport, ok := flowable.HTTPClient.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("expected a tuned *http.Transport, got %T", flowable.HTTPClient.Transport)
+	}
+	if transport.MaxIdleConnsPerHost < 2 || transport.IdleConnTimeout == 0 || transport.TLSHandshakeTimeout == 0 {
+		t.Fatalf("expected production pool settings, got %+v", transport)
+	}
+	if flowable.DefaultTimeouts.Acquire == 0 || flowable.DefaultTimeouts.Report == 0 || flowable.DefaultTimeouts.Query == 0 {
+		t.Fatalf("expected non-zero default timeouts, got %+v", flowable.DefaultTimeouts)
+	}
+}
+
+func TestTimeouts_PerCallKind(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	flowable.SetTimeouts(flowable.Timeouts{Acquire: 50 * time.Millisecond, Query: 80 * time.Millisecond})
+	defer flowable.SetTimeouts(flowable.DefaultTimeouts)
+
+	start := time.Now()
+	_, _, _, err := flowable.Acquire_jobs(flowable.AcquireRequest{Topic: "t", URL: srv.URL})
+	if err == nil || time.Since(start) > 2*time.Second {
+		t.Fatalf("expected acquire to time out quickly, got err=%v after %v", err, time.Since(start))
+	}
+	start = time.Now()
+	if _, _, err := flowable.List_jobs(srv.URL); err == nil || time.Since(start) > 2*time.Second {
+		t.Fatalf("expected query to time out quickly, got err=%v after %v", err, time.Since(start))
+	}
+}
+
+func TestMaxResponseBytes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":"` + strings.Repeat("x", 2048) + `"}`))
+	}))
+	defer srv.Close()
+
+	flowable.SetMaxResponseBytes(1024)
+	defer flowable.SetMaxResponseBytes(flowable.DefaultMaxResponseBytes)
+	if _, _, err := flowable.List_jobs(srv.URL); !errors.Is(err, flowable.ErrResponseTooLarge) {
+		t.Fatalf("expected ErrResponseTooLarge, got %v", err)
+	}
+	flowable.SetMaxResponseBytes(4096)
+	if status, _, err := flowable.List_jobs(srv.URL); err != nil || status != 200 {
+		t.Fatalf("expected success under the limit, got status=%d err=%v", status, err)
+	}
+}
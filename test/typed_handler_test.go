@@ -0,0 +1,133 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flowable/flowable-external-client-golang/flowable"
+)
+
+type orderIn struct {
+	OrderID  int64             `json:"orderId"`
+	Customer string            `json:"customer"`
+	Express  bool              `json:"express"`
+	Lines    []map[string]any  `json:"lines"`
+	Meta     map[string]string `json:"meta"`
+}
+
+type orderOut struct {
+	Total     float64   `json:"total"`
+	Count     int32     `json:"count"`
+	Reference int64     `json:"reference"`
+	Approved  bool      `json:"approved"`
+	Note      string    `json:"note,omitempty"`
+	Shipped   time.Time `json:"shipped"`
+	Items     []string  `json:"items"`
+	Skipped   string    `json:"-"`
+}
+
+func TestTypedHandler_DecodesAndEncodes(t *testing.T) {
+	shipped := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	h := flowable.TypedHandler(func(ctx context.Context, in orderIn) (orderOut, error) {
+		if in.OrderID != 9007199254740993 || in.Customer != "acme" || !in.Express || len(in.Lines) != 2 || in.Meta["k"] != "v" {
+			t.Fatalf("unexpected input: %#v", in)
+		}
+		return orderOut{Total: 12.5, Count: 2, Reference: in.OrderID, Approved: true, Shipped: shipped, Items: []string{"a"}}, nil
+	})
+	body := `{"id":"job1","variables":[` +
+		`{"name":"orderId","type":"long","value":9007199254740993},` +
+		`{"name":"customer","type":"string","value":"acme"},` +
+		`{"name":"express","type":"boolean","value":true},` +
+		`{"name":"lines","type":"json","value":[{"sku":"a"},{"sku":"b"}]},` +
+		`{"name":"meta","type":"json","value":"{\"k\":\"v\"}"},` +
+		`{"name":"initiator","type":"string","value":"admin"}]}`
+	status, res := h(context.Background(), 200, body)
+	if status != flowable.HandlerSuccess {
+		t.Fatalf("expected success, got %v (%+v)", status, res)
+	}
+	want := map[string]string{
+		"total":     "double",
+		"count":     "integer",
+		"reference": "long",
+		"approved":  "boolean",
+		"shipped":   "date",
+		"items":     "json",
+	}
+	if len(res.Variables) != len(want) {
+		t.Fatalf("expected %d variables, got %#v", len(want), res.Variables)
+	}
+	for _, v := range res.Variables {
+		if want[v.Name] != v.Type {
+			t.Fatalf("variable %s: expected type %q, got %q", v.Name, want[v.Name], v.Type)
+		}
+	}
+	if got := flowable.GetVar(res.Variables, "reference"); got != "9007199254740993" {
+		t.Fatalf("expected reference 9007199254740993, got %q", got)
+	}
+}
+
+func TestTypedHandler_FieldLevelDecodeErrors(t *testing.T) {
+	called := false
+	h := flowable.TypedHandler(func(ctx context.Context, in orderIn) (orderOut, error) {
+		called = true
+		return orderOut{}, nil
+	})
+	body := `{"variables":[{"name":"orderId","type":"string","value":"abc"},{"name":"express","type":"string","value":"yes"}]}`
+	status, res := h(context.Background(), 200, body)
+	if status != flowable.HandlerFail {
+		t.Fatalf("expected fail, got %v", status)
+	}
+	if called {
+		t.Fatalf("handler must not be called when decoding fails")
+	}
+	if !strings.Contains(res.ErrorMessage, "OrderID") || !strings.Contains(res.ErrorMessage, "Express") {
+		t.Fatalf("expected both fields in message, got %q", res.ErrorMessage)
+	}
+}
+
+func TestTypedHandler_HandlerError(t *testing.T) {
+	h := flowable.TypedHandler(func(ctx context.Context, in map[string]any) (map[string]any, error) {
+		return nil, errors.New("boom")
+	})
+	status, res := h(context.Background(), 200, `{"variables":[]}`)
+	if status != flowable.HandlerFail || res.ErrorMessage != "boom" {
+		t.Fatalf("expected fail with boom, got %v %+v", status, res)
+	}
+	status, _ = h(context.Background(), 500, "")
+	if status != flowable.HandlerFail {
+		t.Fatalf("expected fail on acquire error, got %v", status)
+	}
+}
+
+func TestDecodeVariables_Errors(t *testing.T) {
+	vars := []flowable.HandlerVariable{{Name: "orderId", Type: "string", Value: "x"}}
+	var in orderIn
+	err := flowable.DecodeVariables(vars, &in)
+	var decodeErr *flowable.DecodeError
+	if !errors.As(err, &decodeErr) || len(decodeErr.Fields) != 1 || decodeErr.Fields[0].Variable != "orderId" {
+		t.Fatalf("expected a single field error for orderId, got %v", err)
+	}
+	if err := flowable.DecodeVariables(vars, in); err == nil {
+		t.Fatalf("expected error for non-pointer target")
+	}
+}
+
+func TestDecodeVariables_JSONStringIntoStringField(t *testing.T) {
+	vars := []flowable.HandlerVariable{
+		{Name: "payload", Type: "json", Value: `{"k":"v"}`},
+		{Name: "meta", Type: "json", Value: `{"k":"v"}`},
+	}
+	var in struct {
+		Payload string            `json:"payload"`
+		Meta    map[string]string `json:"meta"`
+	}
+	if err := flowable.DecodeVariables(vars, &in); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if in.Payload != `{"k":"v"}` || in.Meta["k"] != "v" {
+		t.Fatalf("unexpected input: %#v", in)
+	}
+}
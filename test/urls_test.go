@@ -0,0 +1,112 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/flowable/flowable-external-client-golang/flowable"
+)
+
+// uriRecorder starts a server that records the request URIs it receives.
+func uriRecorder(t *testing.T) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var uris []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		uris = append(uris, r.RequestURI)
+		mu.Unlock()
+		if r.URL.Path == "/flowable-work/external-job-api/acquire/jobs" {
+			io.WriteString(w, `[{"id":"a/b?c"}]`)
+			return
+		}
+		io.WriteString(w, `[]`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), uris...)
+	}
+}
+
+func TestURLs_ContextPathAndTrailingSlash(t *testing.T) {
+	srv, uris := uriRecorder(t)
+	for _, base := range []string{srv.URL + "/flowable-work", srv.URL + "/flowable-work/"} {
+		if _, _, _, err := flowable.Acquire_jobs(flowable.AcquireRequest{Topic: "t", URL: base}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, uri := range uris() {
+		if uri != "/flowable-work/external-job-api/acquire/jobs" {
+			t.Fatalf("unexpected request URI %q", uri)
+		}
+	}
+}
+
+func TestURLs_JobIdIsEscaped(t *testing.T) {
+	flowable.SetEnableLogging(false)
+	t.Cleanup(func() { flowable.SetEnableLogging(true) })
+	srv, uris := uriRecorder(t)
+
+	handled := make(chan struct{}, 1)
+	runSubscription(t, flowable.AcquireRequest{Topic: "t", WorkerId: "w", URL: srv.URL + "/flowable-work", Interval: 10 * time.Millisecond}, handled)
+	<-handled
+	want := "/flowable-work/external-job-api/acquire/jobs/a%2Fb%3Fc/complete"
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, uri := range uris() {
+			if uri == want {
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expected a report to %s, got %v", want, uris())
+}
+
+func TestURLs_APIPrefixAndFilters(t *testing.T) {
+	srv, uris := uriRecorder(t)
+	flowable.SetAPIPrefix("custom/jobs-api/")
+	defer flowable.SetAPIPrefix("")
+
+	_, _, err := flowable.List_jobs_filtered(srv.URL+"/flowable-work", flowable.JobFilter{
+		ProcessInstanceId: "pi 1&x",
+		WithException:     true,
+		Size:              5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "/flowable-work/custom/jobs-api/jobs?processInstanceId=pi+1%26x&size=5&withException=true"
+	if got := uris(); len(got) != 1 || got[0] != want {
+		t.Fatalf("expected %s, got %v", want, got)
+	}
+}
+
+func TestURLs_RejectsMalformedBaseURL(t *testing.T) {
+	for _, base := range []string{"", "localhost:8090/flowable-work", "ftp://host/x", "http:///flowable-work", "http://host/x?y=1"} {
+		if err := flowable.ValidateBaseURL(base); !errors.Is(err, flowable.ErrInvalidBaseURL) {
+			t.Errorf("ValidateBaseURL(%q): expected ErrInvalidBaseURL, got %v", base, err)
+		}
+		if _, _, _, err := flowable.Acquire_jobs(flowable.AcquireRequest{Topic: "t", URL: base}); !errors.Is(err, flowable.ErrInvalidBaseURL) {
+			t.Errorf("Acquire_jobs(%q): expected ErrInvalidBaseURL, got %v", base, err)
+		}
+	}
+	if err := flowable.ValidateBaseURL("https://flowable.example.com/flowable-work"); err != nil {
+		t.Fatal(err)
+	}
+	err := flowable.SubscribeContext(context.Background(), flowable.AcquireRequest{Topic: "t", URL: "localhost:8090"},
+		func(ctx context.Context, status int, body string) (flowable.HandlerStatus, *flowable.HandlerResult) {
+			return flowable.HandlerSuccess, nil
+		})
+	if !errors.Is(err, flowable.ErrInvalidBaseURL) {
+		t.Fatalf("SubscribeContext: expected ErrInvalidBaseURL, got %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package worker_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/flowable/flowable-external-client-golang/flowable"
+)
+
+func TestExtractVariablesFromBody_ObjectSortedByName(t *testing.T) {
+	body := `{"variables":{"zeta":{"type":"string","value":"z"},"alpha":{"type":"string","value":"a"},"mid":{"type":"string","value":"m"}}}`
+	for i := 0; i < 20; i++ {
+		vars, err := flowable.ExtractVariablesFromBody(body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(vars) != 3 || vars[0].Name != "alpha" || vars[1].Name != "mid" || vars[2].Name != "zeta" {
+			t.Fatalf("expected alpha, mid, zeta order, got %#v", vars)
+		}
+	}
+}
+
+func TestExtractVariablesFromBody_ArrayKeepsServerOrder(t *testing.T) {
+	body := `{"variables":[{"name":"c","value":1},{"name":"a","value":2},{"name":"b","value":3}]}`
+	vars, err := flowable.ExtractVariablesFromBody(body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(vars) != 3 || vars[0].Name != "c" || vars[1].Name != "a" || vars[2].Name != "b" {
+		t.Fatalf("expected c, a, b order, got %#v", vars)
+	}
+}
+
+func TestExtractVariablesFromBody_DuplicateNames(t *testing.T) {
+	for _, body := range []string{
+		`{"variables":[{"name":"a","value":1},{"name":"a","value":2}]}`,
+		`{"variables":{"a":{"value":1},"a":{"value":2}}}`,
+	} {
+		_, err := flowable.ExtractVariablesFromBody(body)
+		if !errors.Is(err, flowable.ErrDuplicateVariable) {
+			t.Fatalf("expected ErrDuplicateVariable for %s, got %v", body, err)
+		}
+	}
+}
+
+func TestExtractVariablesFromBody_EmptyName(t *testing.T) {
+	for _, body := range []string{
+		`{"variables":[{"type":"string","value":"x"}]}`,
+		`{"variables":{"":{"value":1}}}`,
+	} {
+		_, err := flowable.ExtractVariablesFromBody(body)
+		if !errors.Is(err, flowable.ErrEmptyVariableName) {
+			t.Fatalf("expected ErrEmptyVariableName for %s, got %v", body, err)
+		}
+	}
+}